@@ -0,0 +1,788 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+const (
+	intTy byte = iota
+	uintTy
+	boolTy
+	addressTy
+	fixedBytesTy
+	functionTy
+	bytesTy
+	stringTy
+	sliceTy
+	arrayTy
+	tupleTy
+)
+
+const abiWordSize = 32
+
+var errShortAbiData = errors.New("abi: data too short")
+
+// AbiType is a parsed ABI type. Values are decoded to *big.Int (int/uint),
+// bool, common.Address, []byte (bytes, bytesN, function), string and
+// []interface{} (arrays and tuples).
+type AbiType struct {
+	T          byte
+	Size       int // bits for int/uint, bytes for bytesN, length for T[k]
+	Elem       *AbiType
+	Components []AbiArgument
+	str        string
+}
+
+func (a *AbiArgument) UnmarshalJSON(data []byte) error {
+	var arg struct {
+		Name       string
+		Type       string
+		Indexed    bool
+		Components []AbiArgument
+	}
+
+	if err := json.Unmarshal(data, &arg); err != nil {
+		return err
+	}
+
+	typ, err := newAbiType(arg.Type, arg.Components)
+	if err != nil {
+		return err
+	}
+
+	a.Name = arg.Name
+	a.Type = typ
+	a.Indexed = arg.Indexed
+
+	return nil
+}
+
+func newAbiType(typ string, components []AbiArgument) (*AbiType, error) {
+	typ = strings.TrimSpace(typ)
+
+	if strings.HasSuffix(typ, "]") {
+		i := strings.LastIndex(typ, "[")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid ABI type: %s", typ)
+		}
+
+		elem, err := newAbiType(typ[:i], components)
+		if err != nil {
+			return nil, err
+		}
+
+		dim := typ[i+1 : len(typ)-1]
+		if dim == "" {
+			return &AbiType{T: sliceTy, Elem: elem, str: elem.str + "[]"}, nil
+		}
+
+		size, err := strconv.Atoi(dim)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid ABI array size: %s", typ)
+		}
+
+		return &AbiType{T: arrayTy, Size: size, Elem: elem, str: elem.str + "[" + dim + "]"}, nil
+	}
+
+	switch {
+	case typ == "tuple":
+		if len(components) == 0 {
+			return nil, errors.New("invalid ABI type: tuple without components")
+		}
+
+		typeNames := make([]string, len(components))
+		for i, component := range components {
+			typeNames[i] = component.Type.String()
+		}
+
+		return &AbiType{T: tupleTy, Components: components, str: "(" + strings.Join(typeNames, ",") + ")"}, nil
+
+	case typ == "bool":
+		return &AbiType{T: boolTy, str: typ}, nil
+
+	case typ == "address":
+		return &AbiType{T: addressTy, Size: 20, str: typ}, nil
+
+	case typ == "string":
+		return &AbiType{T: stringTy, str: typ}, nil
+
+	case typ == "bytes":
+		return &AbiType{T: bytesTy, str: typ}, nil
+
+	case typ == "function":
+		return &AbiType{T: functionTy, Size: 24, str: typ}, nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid ABI type: %s", typ)
+		}
+
+		return &AbiType{T: fixedBytesTy, Size: size, str: typ}, nil
+
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		t := &AbiType{T: intTy, Size: 256}
+		name := "int"
+
+		if strings.HasPrefix(typ, "uint") {
+			t.T = uintTy
+			name = "uint"
+		}
+
+		if bits := typ[len(name):]; bits != "" {
+			size, err := strconv.Atoi(bits)
+			if err != nil || size < 8 || size > 256 || size%8 != 0 {
+				return nil, fmt.Errorf("invalid ABI type: %s", typ)
+			}
+			t.Size = size
+		}
+
+		t.str = name + strconv.Itoa(t.Size)
+
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("unsupported ABI type: %s", typ)
+}
+
+// String returns the canonical type name, as used in signatures.
+func (t *AbiType) String() string {
+	return t.str
+}
+
+func (t *AbiType) componentTypes() []*AbiType {
+	argTypes := make([]*AbiType, len(t.Components))
+	for i, component := range t.Components {
+		argTypes[i] = component.Type
+	}
+	return argTypes
+}
+
+func (t *AbiType) isDynamic() bool {
+	switch t.T {
+	case bytesTy, stringTy, sliceTy:
+		return true
+	case arrayTy:
+		return t.Elem.isDynamic()
+	case tupleTy:
+		for _, component := range t.Components {
+			if component.Type.isDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHashedTopic reports whether an indexed event argument of this type is
+// stored in its topic as the keccak256 hash of its value.
+func (t *AbiType) isHashedTopic() bool {
+	switch t.T {
+	case bytesTy, stringTy, sliceTy, arrayTy, tupleTy:
+		return true
+	}
+	return false
+}
+
+// headSize returns the number of bytes the type takes in the head part of an
+// encoding. Dynamic types only store an offset there.
+func (t *AbiType) headSize() int {
+	if t.isDynamic() {
+		return abiWordSize
+	}
+
+	switch t.T {
+	case arrayTy:
+		return t.Size * t.Elem.headSize()
+	case tupleTy:
+		size := 0
+		for _, component := range t.Components {
+			size += component.Type.headSize()
+		}
+		return size
+	}
+
+	return abiWordSize
+}
+
+func repeatAbiType(t *AbiType, n int) []*AbiType {
+	argTypes := make([]*AbiType, n)
+	for i := range argTypes {
+		argTypes[i] = t
+	}
+	return argTypes
+}
+
+// decodeAbiValues decodes a sequence of values using the head/tail layout
+// shared by call data, return data, event data, arrays and tuples. Offsets of
+// dynamic values are relative to the start of data.
+func decodeAbiValues(argTypes []*AbiType, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(argTypes))
+	head := 0
+
+	for i, t := range argTypes {
+		var value interface{}
+		var err error
+
+		if head > len(data) {
+			return nil, errShortAbiData
+		}
+
+		if t.isDynamic() {
+			offset, err := abiInt(data, head, len(data))
+			if err != nil {
+				return nil, err
+			}
+
+			value, err = t.decode(data[offset:])
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value, err = t.decode(data[head:])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		values[i] = value
+		head += t.headSize()
+	}
+
+	return values, nil
+}
+
+// decode decodes a single value starting at the beginning of data.
+func (t *AbiType) decode(data []byte) (interface{}, error) {
+	switch t.T {
+	case bytesTy, stringTy:
+		length, err := abiInt(data, 0, len(data)-abiWordSize)
+		if err != nil {
+			return nil, err
+		}
+
+		value := data[abiWordSize : abiWordSize+length]
+		if t.T == stringTy {
+			return string(value), nil
+		}
+
+		return common.CopyBytes(value), nil
+
+	case sliceTy:
+		if len(data) < abiWordSize {
+			return nil, errShortAbiData
+		}
+
+		length, err := abiInt(data, 0, (len(data)-abiWordSize)/t.Elem.headSize())
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeAbiValues(repeatAbiType(t.Elem, length), data[abiWordSize:])
+
+	case arrayTy:
+		return decodeAbiValues(repeatAbiType(t.Elem, t.Size), data)
+
+	case tupleTy:
+		return decodeAbiValues(t.componentTypes(), data)
+	}
+
+	if len(data) < abiWordSize {
+		return nil, errShortAbiData
+	}
+
+	word := data[:abiWordSize]
+
+	switch t.T {
+	case uintTy:
+		value := new(big.Int).SetBytes(word)
+		if value.BitLen() > t.Size {
+			return nil, fmt.Errorf("abi: value out of range for %s", t)
+		}
+		return value, nil
+
+	case intTy:
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+
+		// the value must be sign extended from its size
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if value.Cmp(limit) >= 0 || value.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("abi: value out of range for %s", t)
+		}
+		return value, nil
+
+	case boolTy:
+		value := new(big.Int).SetBytes(word)
+		if value.BitLen() > 1 {
+			return nil, errors.New("abi: invalid bool value")
+		}
+		return value.BitLen() == 1, nil
+
+	case addressTy:
+		if !isZeroBytes(word[:12]) {
+			return nil, errors.New("abi: invalid address value")
+		}
+		return common.BytesToAddress(word[12:]), nil
+
+	case fixedBytesTy, functionTy:
+		if !isZeroBytes(word[t.Size:]) {
+			return nil, fmt.Errorf("abi: invalid %s value", t)
+		}
+		return common.CopyBytes(word[:t.Size]), nil
+	}
+
+	return nil, fmt.Errorf("abi: cannot decode %s", t)
+}
+
+func isZeroBytes(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// abiInt reads the word at pos as a length or offset, which may not exceed max.
+func abiInt(data []byte, pos int, max int) (int, error) {
+	if pos < 0 || pos+abiWordSize > len(data) {
+		return 0, errShortAbiData
+	}
+
+	value := new(big.Int).SetBytes(data[pos : pos+abiWordSize])
+	if max < 0 || !value.IsInt64() || value.Int64() > int64(max) {
+		return 0, fmt.Errorf("abi: offset or length %s out of bounds", value)
+	}
+
+	return int(value.Int64()), nil
+}
+
+// decodeEventValues decodes the inputs of an event, taking indexed values
+// from the log topics and the rest from the log data. Indexed strings, bytes,
+// arrays and tuples are only available as hashes and are returned as a
+// common.Hash.
+func decodeEventValues(event *AbiMethod, log *types.Log) ([]interface{}, error) {
+	topics := log.Topics
+	if !event.Anonymous {
+		if len(topics) == 0 {
+			return nil, errors.New("abi: log has no topics")
+		}
+		topics = topics[1:]
+	}
+
+	values := make([]interface{}, len(event.Inputs))
+	dataTypes := []*AbiType{}
+
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			dataTypes = append(dataTypes, input.Type)
+			continue
+		}
+
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("abi: missing topic for indexed input %s", input.Name)
+		}
+
+		topic := topics[0]
+		topics = topics[1:]
+
+		if input.Type.isHashedTopic() {
+			values[i] = topic
+			continue
+		}
+
+		value, err := input.Type.decode(topic.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	dataValues, err := decodeAbiValues(dataTypes, log.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			values[i] = dataValues[0]
+			dataValues = dataValues[1:]
+		}
+	}
+
+	return values, nil
+}
+
+// formatAbiValue renders a decoded value for display.
+func formatAbiValue(t *AbiType, value interface{}) string {
+	if hash, ok := value.(common.Hash); ok {
+		return hash.Hex()
+	}
+
+	switch t.T {
+	case intTy, uintTy:
+		return value.(*big.Int).String()
+
+	case boolTy:
+		return strconv.FormatBool(value.(bool))
+
+	case addressTy:
+		return value.(common.Address).Hex()
+
+	case fixedBytesTy, functionTy, bytesTy:
+		return "0x" + hex.EncodeToString(value.([]byte))
+
+	case stringTy:
+		return strconv.Quote(value.(string))
+
+	case sliceTy, arrayTy:
+		items := value.([]interface{})
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = formatAbiValue(t.Elem, item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+
+	case tupleTy:
+		items := value.([]interface{})
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = formatAbiValue(t.Components[i].Type, item)
+			if name := t.Components[i].Name; name != "" {
+				parts[i] = name + ": " + parts[i]
+			}
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	}
+
+	return fmt.Sprint(value)
+}
+
+// jsonAbiValue converts a decoded value for JSON output. Integers become
+// decimal strings and byte values hex strings, so no precision is lost.
+// Tuples become objects when all of their components are named.
+func jsonAbiValue(t *AbiType, value interface{}) interface{} {
+	if hash, ok := value.(common.Hash); ok {
+		return hash.Hex()
+	}
+
+	switch t.T {
+	case intTy, uintTy:
+		return value.(*big.Int).String()
+
+	case addressTy:
+		return value.(common.Address).Hex()
+
+	case fixedBytesTy, functionTy, bytesTy:
+		return "0x" + hex.EncodeToString(value.([]byte))
+
+	case sliceTy, arrayTy:
+		items := value.([]interface{})
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = jsonAbiValue(t.Elem, item)
+		}
+		return list
+
+	case tupleTy:
+		items := value.([]interface{})
+		list := make([]interface{}, len(items))
+		fields := map[string]interface{}{}
+
+		for i, item := range items {
+			list[i] = jsonAbiValue(t.Components[i].Type, item)
+			if name := t.Components[i].Name; name != "" {
+				fields[name] = list[i]
+			}
+		}
+
+		if len(fields) == len(items) {
+			return fields
+		}
+		return list
+	}
+
+	return value
+}
+
+// encodeAbiValues encodes a sequence of values with the head/tail layout.
+// It is the inverse of decodeAbiValues.
+func encodeAbiValues(argTypes []*AbiType, values []interface{}) ([]byte, error) {
+	if len(values) != len(argTypes) {
+		return nil, fmt.Errorf("abi: expected %d values, got %d", len(argTypes), len(values))
+	}
+
+	headSize := 0
+	for _, t := range argTypes {
+		headSize += t.headSize()
+	}
+
+	head := []byte{}
+	tail := []byte{}
+
+	for i, t := range argTypes {
+		encoded, err := t.encode(values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if t.isDynamic() {
+			head = append(head, abiWord(big.NewInt(int64(headSize+len(tail))))...)
+			tail = append(tail, encoded...)
+		} else {
+			head = append(head, encoded...)
+		}
+	}
+
+	return append(head, tail...), nil
+}
+
+// encode encodes a single value of the types returned by decode.
+func (t *AbiType) encode(value interface{}) ([]byte, error) {
+	switch t.T {
+	case intTy, uintTy:
+		v, ok := value.(*big.Int)
+		if !ok {
+			return nil, fmt.Errorf("abi: %s value must be an integer", t)
+		}
+
+		if t.T == uintTy && (v.Sign() < 0 || v.BitLen() > t.Size) {
+			return nil, fmt.Errorf("abi: value %s out of range for %s", v, t)
+		}
+
+		if t.T == intTy {
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+			if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("abi: value %s out of range for %s", v, t)
+			}
+
+			if v.Sign() < 0 {
+				// two's complement
+				v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+		}
+
+		return abiWord(v), nil
+
+	case boolTy:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("abi: %s value must be a bool", t)
+		}
+
+		if v {
+			return abiWord(big.NewInt(1)), nil
+		}
+		return abiWord(new(big.Int)), nil
+
+	case addressTy:
+		v, ok := value.(common.Address)
+		if !ok {
+			return nil, fmt.Errorf("abi: %s value must be an address", t)
+		}
+
+		return common.LeftPadBytes(v.Bytes(), abiWordSize), nil
+
+	case fixedBytesTy, functionTy:
+		v, ok := value.([]byte)
+		if !ok || len(v) != t.Size {
+			return nil, fmt.Errorf("abi: %s value must be %d bytes", t, t.Size)
+		}
+
+		return common.RightPadBytes(v, abiWordSize), nil
+
+	case bytesTy, stringTy:
+		var v []byte
+
+		switch value := value.(type) {
+		case []byte:
+			v = value
+		case string:
+			v = []byte(value)
+		default:
+			return nil, fmt.Errorf("abi: invalid %s value", t)
+		}
+
+		padded := (len(v) + abiWordSize - 1) / abiWordSize * abiWordSize
+
+		return append(abiWord(big.NewInt(int64(len(v)))), common.RightPadBytes(v, padded)...), nil
+
+	case sliceTy, arrayTy, tupleTy:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("abi: %s value must be a list", t)
+		}
+
+		switch t.T {
+		case sliceTy:
+			encoded, err := encodeAbiValues(repeatAbiType(t.Elem, len(items)), items)
+			if err != nil {
+				return nil, err
+			}
+			return append(abiWord(big.NewInt(int64(len(items)))), encoded...), nil
+
+		case arrayTy:
+			return encodeAbiValues(repeatAbiType(t.Elem, t.Size), items)
+
+		default:
+			return encodeAbiValues(t.componentTypes(), items)
+		}
+	}
+
+	return nil, fmt.Errorf("abi: cannot encode %s", t)
+}
+
+// abiWord returns a non-negative integer as a 32 byte word.
+func abiWord(v *big.Int) []byte {
+	return common.LeftPadBytes(v.Bytes(), abiWordSize)
+}
+
+// parseAbiArguments parses command line arguments into values of the input
+// types, ready to be encoded.
+func parseAbiArguments(inputs []AbiArgument, args []string) ([]interface{}, error) {
+	if len(args) != len(inputs) {
+		return nil, fmt.Errorf("expected %d arguments (%s), got %d", len(inputs), getInputNamesString(inputs), len(args))
+	}
+
+	values := make([]interface{}, len(inputs))
+
+	for i, input := range inputs {
+		value, err := parseAbiValue(input.Type, args[i])
+		if err != nil {
+			if input.Name != "" {
+				return nil, fmt.Errorf("%s: %s", input.Name, err)
+			}
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// parseAbiValue parses a value from its text form. Integers are decimal or
+// 0x hex, byte values are hex and addresses may be address book labels.
+// Arrays and tuples are JSON arrays, and tuples may also be JSON objects
+// keyed by component name.
+func parseAbiValue(t *AbiType, input string) (interface{}, error) {
+	switch t.T {
+	case intTy, uintTy:
+		value, ok := new(big.Int).SetString(input, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value: %s", t, input)
+		}
+		return value, nil
+
+	case boolTy:
+		value, err := strconv.ParseBool(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %s", t, input)
+		}
+		return value, nil
+
+	case addressTy:
+		address, _, err := resolveAddress(input)
+		if err != nil {
+			return nil, err
+		}
+		return address, nil
+
+	case fixedBytesTy, functionTy, bytesTy:
+		value, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %s", t, input)
+		}
+
+		if t.T != bytesTy && len(value) != t.Size {
+			return nil, fmt.Errorf("invalid %s value: expected %d bytes, got %d", t, t.Size, len(value))
+		}
+
+		return value, nil
+
+	case stringTy:
+		return input, nil
+	}
+
+	items, err := splitAbiList(t, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemTypes []*AbiType
+
+	switch t.T {
+	case sliceTy:
+		itemTypes = repeatAbiType(t.Elem, len(items))
+
+	case arrayTy:
+		if len(items) != t.Size {
+			return nil, fmt.Errorf("invalid %s value: expected %d items, got %d", t, t.Size, len(items))
+		}
+		itemTypes = repeatAbiType(t.Elem, t.Size)
+
+	case tupleTy:
+		if len(items) != len(t.Components) {
+			return nil, fmt.Errorf("invalid %s value: expected %d items, got %d", t, len(t.Components), len(items))
+		}
+		itemTypes = t.componentTypes()
+	}
+
+	values := make([]interface{}, len(items))
+
+	for i, item := range items {
+		values[i], err = parseAbiValue(itemTypes[i], item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// splitAbiList splits the JSON form of an array or tuple into the text
+// forms of its items. JSON strings are unquoted, other values kept as is.
+func splitAbiList(t *AbiType, input string) ([]string, error) {
+	var raw []json.RawMessage
+
+	if t.T == tupleTy && strings.HasPrefix(strings.TrimSpace(input), "{") {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(input), &fields); err != nil {
+			return nil, fmt.Errorf("invalid %s value: %s", t, err)
+		}
+
+		for _, component := range t.Components {
+			field, ok := fields[component.Name]
+			if !ok {
+				return nil, fmt.Errorf("invalid %s value: missing %s", t, component.Name)
+			}
+			raw = append(raw, field)
+		}
+	} else if err := json.Unmarshal([]byte(input), &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s value, expected a JSON array: %s", t, input)
+	}
+
+	items := make([]string, len(raw))
+
+	for i, item := range raw {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			items[i] = s
+		} else {
+			items[i] = string(item)
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// words joins 32-byte words written in hex, as in the Solidity ABI
+// specification examples.
+func words(ws ...string) string {
+	return strings.Join(ws, "")
+}
+
+func TestPackMethodCall(t *testing.T) {
+	tests := []struct {
+		sig  string
+		args []string
+		want string
+	}{
+		{
+			sig:  "transfer(address,uint256)",
+			args: []string{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "1000000000000000000"},
+			want: "a9059cbb" + words(
+				"0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+				"0000000000000000000000000000000000000000000000000de0b6b3a7640000",
+			),
+		},
+		{
+			sig:  "f(int8,int8,int256)",
+			args: []string{"-1", "-128", "-2"},
+			want: words(
+				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80",
+				"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe",
+			),
+		},
+		{
+			sig:  "f(bytes)",
+			args: []string{"0x1234"},
+			want: words(
+				"0000000000000000000000000000000000000000000000000000000000000020",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"1234000000000000000000000000000000000000000000000000000000000000",
+			),
+		},
+		{
+			// Solidity ABI specification, dynamic types example
+			sig:  "f(uint256,uint32[],bytes10,bytes)",
+			args: []string{"0x123", "[1110,1929]", "0x31323334353637383930", "0x48656c6c6f2c20776f726c6421"},
+			want: "8be65246" + words(
+				"0000000000000000000000000000000000000000000000000000000000000123",
+				"0000000000000000000000000000000000000000000000000000000000000080",
+				"3132333435363738393000000000000000000000000000000000000000000000",
+				"00000000000000000000000000000000000000000000000000000000000000e0",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"0000000000000000000000000000000000000000000000000000000000000456",
+				"0000000000000000000000000000000000000000000000000000000000000789",
+				"000000000000000000000000000000000000000000000000000000000000000d",
+				"48656c6c6f2c20776f726c642100000000000000000000000000000000000000",
+			),
+		},
+		{
+			// Solidity ABI specification, nested dynamic arrays example
+			sig:  "g(uint256[][],string[])",
+			args: []string{"[[1,2],[3]]", `["one","two","three"]`},
+			want: "2289b18c" + words(
+				"0000000000000000000000000000000000000000000000000000000000000040",
+				"0000000000000000000000000000000000000000000000000000000000000140",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"0000000000000000000000000000000000000000000000000000000000000040",
+				"00000000000000000000000000000000000000000000000000000000000000a0",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"0000000000000000000000000000000000000000000000000000000000000001",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"0000000000000000000000000000000000000000000000000000000000000001",
+				"0000000000000000000000000000000000000000000000000000000000000003",
+				"0000000000000000000000000000000000000000000000000000000000000003",
+				"0000000000000000000000000000000000000000000000000000000000000060",
+				"00000000000000000000000000000000000000000000000000000000000000a0",
+				"00000000000000000000000000000000000000000000000000000000000000e0",
+				"0000000000000000000000000000000000000000000000000000000000000003",
+				"6f6e650000000000000000000000000000000000000000000000000000000000",
+				"0000000000000000000000000000000000000000000000000000000000000003",
+				"74776f0000000000000000000000000000000000000000000000000000000000",
+				"0000000000000000000000000000000000000000000000000000000000000005",
+				"7468726565000000000000000000000000000000000000000000000000000000",
+			),
+		},
+		{
+			sig:  "f((uint256,address),bool)",
+			args: []string{`[7,"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"]`, "true"},
+			want: words(
+				"0000000000000000000000000000000000000000000000000000000000000007",
+				"0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+				"0000000000000000000000000000000000000000000000000000000000000001",
+			),
+		},
+		{
+			sig:  "f((uint256,string))",
+			args: []string{`[7,"ab"]`},
+			want: words(
+				"0000000000000000000000000000000000000000000000000000000000000020",
+				"0000000000000000000000000000000000000000000000000000000000000007",
+				"0000000000000000000000000000000000000000000000000000000000000040",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+				"6162000000000000000000000000000000000000000000000000000000000000",
+			),
+		},
+	}
+
+	for _, test := range tests {
+		method, err := parseSignature(test.sig)
+		if err != nil {
+			t.Fatalf("%s: %s", test.sig, err)
+		}
+
+		data, err := packMethodCall(method, test.args)
+		if err != nil {
+			t.Errorf("%s: %s", test.sig, err)
+			continue
+		}
+
+		want := test.want
+		if len(want)%64 == 0 {
+			// the selector is only checked for the known examples
+			want = hex.EncodeToString(data[:4]) + want
+		}
+
+		if got := hex.EncodeToString(data); got != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.sig, got, want)
+			continue
+		}
+
+		// decoding and encoding again gives the same data
+		argTypes := getArgumentTypes(method.Inputs)
+
+		values, err := decodeAbiValues(argTypes, data[4:])
+		if err != nil {
+			t.Errorf("%s: decode: %s", test.sig, err)
+			continue
+		}
+
+		encoded, err := encodeAbiValues(argTypes, values)
+		if err != nil {
+			t.Errorf("%s: encode: %s", test.sig, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(encoded); got != hex.EncodeToString(data[4:]) {
+			t.Errorf("%s: round trip:\ngot  %s\nwant %x", test.sig, got, data[4:])
+		}
+	}
+}
+
+func TestEncodeOutOfRange(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value *big.Int
+	}{
+		{"int8", big.NewInt(128)},
+		{"int8", big.NewInt(-129)},
+		{"uint8", big.NewInt(256)},
+		{"uint256", big.NewInt(-1)},
+	}
+
+	for _, test := range tests {
+		typ, err := newAbiType(test.typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := typ.encode(test.value); err == nil {
+			t.Errorf("%s %s: expected an error", test.typ, test.value)
+		}
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		typ  string
+		word string
+		want string // empty when decoding must fail
+	}{
+		{"int8", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80", "-128"},
+		{"int8", "000000000000000000000000000000000000000000000000000000000000007f", "127"},
+		{"int8", "0000000000000000000000000000000000000000000000000000000000000080", ""},
+		{"int8", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", ""},
+		{"uint8", "0000000000000000000000000000000000000000000000000000000000000100", ""},
+		{"bool", "0000000000000000000000000000000000000000000000000000000000000002", ""},
+		{"address", "0000000000000000000000015aaeb6053f3e94c9b9a09f33669435e7ef1beaed", ""},
+		{"bytes2", "1234000000000000000000000000000000000000000000000000000000000000", "0x1234"},
+		{"bytes2", "1234000000000000000000000000000000000000000000000000000000000001", ""},
+	}
+
+	for _, test := range tests {
+		typ, err := newAbiType(test.typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := hex.DecodeString(test.word)
+		value, err := typ.decode(data)
+
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%s %s: expected an error, got %v", test.typ, test.word, value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s %s: %s", test.typ, test.word, err)
+			continue
+		}
+
+		if got := formatAbiValue(typ, value); got != test.want {
+			t.Errorf("%s %s: got %s, want %s", test.typ, test.word, got, test.want)
+		}
+	}
+}
+
+func TestDecodeBadOffset(t *testing.T) {
+	typ, _ := newAbiType("bytes", nil)
+
+	// the offset points past the end of the data
+	data, _ := hex.DecodeString(words(
+		"0000000000000000000000000000000000000000000000000000000000000040",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+	))
+
+	if _, err := decodeAbiValues([]*AbiType{typ}, data); err == nil {
+		t.Error("expected an error")
+	}
+}
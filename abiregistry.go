@@ -0,0 +1,375 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+// configContract is an entry of the config contracts map. An entry is either
+// a token address, or a map with the address and optionally the ABI file of
+// the contract:
+//
+//	contracts:
+//	  WETH: 0x46397994a7e1e926ea0de95557a4806d38f10b0d
+//	  htlc:
+//	    address: 0x...
+//	    abi: wethhtlc.abi
+//
+// Map entries with an ABI are only treated as tokens with token: true.
+type configContract struct {
+	Name    string
+	Address common.Address
+	Abi     string
+	Token   bool
+}
+
+// getConfigContracts returns the config contracts by lower case name, as
+// viper keys are case insensitive.
+func getConfigContracts() (map[string]configContract, error) {
+	contracts := map[string]configContract{}
+
+	for name, value := range viper.GetStringMap("contracts") {
+		contract := configContract{Name: name, Token: true}
+		var address string
+
+		switch value := value.(type) {
+		case string:
+			address = value
+
+		case map[string]interface{}, map[interface{}]interface{}:
+			fields := map[string]interface{}{}
+
+			if m, ok := value.(map[string]interface{}); ok {
+				fields = m
+			} else {
+				for k, v := range value.(map[interface{}]interface{}) {
+					fields[fmt.Sprint(k)] = v
+				}
+			}
+
+			address = fmt.Sprint(fields["address"])
+			if abi, ok := fields["abi"]; ok {
+				contract.Abi = fmt.Sprint(abi)
+			}
+			if token, ok := fields["token"].(bool); ok {
+				contract.Token = token
+			} else {
+				contract.Token = contract.Abi == ""
+			}
+		}
+
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("Invalid address for contract %s in config", name)
+		}
+
+		contract.Address = common.HexToAddress(address)
+		contracts[strings.ToLower(name)] = contract
+	}
+
+	return contracts, nil
+}
+
+// getConfigContract looks up a config contract by name.
+func getConfigContract(name string) (configContract, bool, error) {
+	contracts, err := getConfigContracts()
+	if err != nil {
+		return configContract{}, false, err
+	}
+
+	contract, ok := contracts[strings.ToLower(name)]
+	return contract, ok, nil
+}
+
+// abiIndex indexes the methods of ABIs by selector and the events by topic.
+type abiIndex struct {
+	fields  []AbiField
+	methods map[[4]byte]*AbiMethod
+	events  map[common.Hash][]*AbiMethod
+}
+
+func newAbiIndex() *abiIndex {
+	return &abiIndex{
+		methods: map[[4]byte]*AbiMethod{},
+		events:  map[common.Hash][]*AbiMethod{},
+	}
+}
+
+// add indexes the fields of an ABI. Selectors and events already indexed
+// keep their first entry, unless override is set, which gives precedence to
+// the new ABI.
+func (i *abiIndex) add(fields []AbiField, override bool) {
+	i.fields = append(i.fields, fields...)
+
+	for _, field := range fields {
+		if field.Name == "" {
+			continue
+		}
+
+		sig, sigHash := buildSignature(&field)
+		method := &AbiMethod{
+			AbiField:      field,
+			Signature:     sig,
+			SignatureHash: sigHash,
+		}
+
+		hash := common.HexToHash(sigHash)
+
+		if field.Type == "event" {
+			// the same event may be declared with different indexed
+			// inputs, such as ERC20 and ERC721 Transfer
+			if override {
+				i.events[hash] = append([]*AbiMethod{method}, i.events[hash]...)
+			} else {
+				i.events[hash] = append(i.events[hash], method)
+			}
+			continue
+		}
+
+		if field.Type != "" && field.Type != "function" {
+			continue
+		}
+
+		var selector [4]byte
+		copy(selector[:], hash[:4])
+
+		if _, ok := i.methods[selector]; !ok || override {
+			i.methods[selector] = method
+		}
+	}
+}
+
+func (i *abiIndex) method(data []byte) *AbiMethod {
+	if len(data) < 4 {
+		return nil
+	}
+
+	var selector [4]byte
+	copy(selector[:], data[:4])
+
+	return i.methods[selector]
+}
+
+// event returns the event of the log topic whose indexed inputs match the
+// topics of the log.
+func (i *abiIndex) event(log *types.Log) *AbiMethod {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+
+	candidates := i.events[log.Topics[0]]
+
+	for _, event := range candidates {
+		indexed := 0
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed++
+			}
+		}
+
+		if indexed == len(log.Topics)-1 {
+			return event
+		}
+	}
+
+	return nil
+}
+
+// abiRegistry holds the known ABIs: the files in the abidir of the selected
+// network and the ABIs of the config contracts. ABI files in the abidir are
+// bound to a contract when named after its address or config name, e.g.
+// htlc.abi. Methods and events are looked up in the ABIs of the contract
+// first, then in all ABIs and last in the signature database.
+type abiRegistry struct {
+	contracts  map[common.Address]*abiIndex
+	all        *abiIndex
+	signatures *signatureDB
+}
+
+func loadAbiRegistry() (*abiRegistry, error) {
+	registry := &abiRegistry{
+		contracts: map[common.Address]*abiIndex{},
+		all:       newAbiIndex(),
+	}
+
+	contracts, err := getConfigContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	registry.signatures, err = loadSignatureDB()
+	if err != nil {
+		return nil, err
+	}
+
+	if abiDir := viper.GetString("abidir"); abiDir != "" {
+		abiDir = expandHome(abiDir)
+
+		files, err := ioutil.ReadDir(abiDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		for _, file := range files {
+			ext := filepath.Ext(file.Name())
+			if file.IsDir() || (ext != ".abi" && ext != ".json") {
+				continue
+			}
+
+			fields, err := parseAbi(filepath.Join(abiDir, file.Name()))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping ABI file %s: %s\n", file.Name(), err)
+				continue
+			}
+
+			name := strings.TrimSuffix(file.Name(), ext)
+
+			var address *common.Address
+			if common.IsHexAddress(name) {
+				bound := common.HexToAddress(name)
+				address = &bound
+			} else if contract, ok := contracts[strings.ToLower(name)]; ok {
+				address = &contract.Address
+			}
+
+			registry.add(address, fields)
+		}
+	}
+
+	// sorted, so lookups do not depend on the map order
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		contract := contracts[name]
+		if contract.Abi == "" {
+			continue
+		}
+
+		fields, err := parseAbi(contract.Abi)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping ABI of contract %s: %s\n", contract.Name, err)
+			continue
+		}
+
+		registry.add(&contract.Address, fields)
+	}
+
+	return registry, nil
+}
+
+// add indexes an ABI, bound to the contract address when not nil.
+func (r *abiRegistry) add(address *common.Address, fields []AbiField) {
+	r.all.add(fields, false)
+
+	if address == nil {
+		return
+	}
+
+	index, ok := r.contracts[*address]
+	if !ok {
+		index = newAbiIndex()
+		r.contracts[*address] = index
+	}
+
+	index.add(fields, false)
+}
+
+// override indexes an ABI given explicitly, such as with --abi. It replaces
+// the ABI bound to the contract address and takes precedence over the other
+// known ABIs.
+func (r *abiRegistry) override(address *common.Address, fields []AbiField) {
+	r.all.add(fields, true)
+
+	if address == nil {
+		return
+	}
+
+	index := newAbiIndex()
+	index.add(fields, true)
+	r.contracts[*address] = index
+}
+
+// contractAbi returns the ABI bound to a contract address.
+func (r *abiRegistry) contractAbi(address common.Address) []AbiField {
+	if index, ok := r.contracts[address]; ok {
+		return index.fields
+	}
+	return nil
+}
+
+// method finds the method called by the transaction input.
+func (r *abiRegistry) method(to *common.Address, data []byte) *AbiMethod {
+	if to != nil {
+		if index, ok := r.contracts[*to]; ok {
+			if method := index.method(data); method != nil {
+				return method
+			}
+		}
+	}
+
+	if method := r.all.method(data); method != nil {
+		return method
+	}
+
+	return r.signatures.method(data)
+}
+
+// decodeCall decodes a transaction input by its leading 4-byte selector. It
+// returns nil for unknown selectors, and a call with an error when the input
+// is not a valid call.
+func (r *abiRegistry) decodeCall(to *common.Address, data []byte) *decodedCall {
+	if len(data) < 4 {
+		return &decodedCall{
+			Selector: hexutil.Encode(data),
+			Error:    fmt.Sprintf("input of %d bytes is too short for a method selector", len(data)),
+		}
+	}
+
+	method := r.method(to, data)
+	if method == nil {
+		return nil
+	}
+
+	return newDecodedCall(method, data[4:])
+}
+
+// event finds the event of a log.
+func (r *abiRegistry) event(log *types.Log) *AbiMethod {
+	if index, ok := r.contracts[log.Address]; ok {
+		if event := index.event(log); event != nil {
+			return event
+		}
+	}
+
+	if event := r.all.event(log); event != nil {
+		return event
+	}
+
+	return r.signatures.event(log)
+}
+
+// decodeLogs decodes the logs with a known event.
+func (r *abiRegistry) decodeLogs(logs []*types.Log) []*decodedEvent {
+	decoded := []*decodedEvent{}
+
+	for _, log := range logs {
+		if event := r.event(log); event != nil {
+			decoded = append(decoded, newDecodedEvent(event, log))
+		}
+	}
+
+	return decoded
+}
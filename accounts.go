@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/urfave/cli"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// accountLabels maps labels to the addresses of the keystore accounts. They
+// are kept in ~/.wanutil/accounts.json, next to the keystore directory.
+type accountLabels struct {
+	Labels map[string]common.Address `json:"labels"`
+
+	path string
+}
+
+func loadAccountLabels() (*accountLabels, error) {
+	path, err := getWanutilPath("accounts.json")
+	if err != nil {
+		return nil, err
+	}
+
+	labels := &accountLabels{
+		Labels: map[string]common.Address{},
+		path:   path,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, labels); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if labels.Labels == nil {
+		labels.Labels = map[string]common.Address{}
+	}
+
+	return labels, nil
+}
+
+func (l *accountLabels) save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(l.path, data, 0600)
+}
+
+// set labels an address, failing when the label is taken by another one.
+func (l *accountLabels) set(label string, address common.Address) error {
+	if common.IsHexAddress(label) {
+		return errors.New("A label cannot be an address")
+	}
+
+	if existing, ok := l.Labels[label]; ok && existing != address {
+		return fmt.Errorf("Label %s is already used by %s", label, existing.Hex())
+	}
+
+	l.Labels[label] = address
+	return nil
+}
+
+// labelOf returns the label of an address, if it has one.
+func (l *accountLabels) labelOf(address common.Address) string {
+	for label, labeled := range l.Labels {
+		if labeled == address {
+			return label
+		}
+	}
+	return ""
+}
+
+func openKeyStore() (*keystore.KeyStore, error) {
+	dir, err := getWanutilPath("keystore")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP), nil
+}
+
+// findAccount finds a keystore account by label or address.
+func findAccount(ks *keystore.KeyStore, labels *accountLabels, input string) (accounts.Account, error) {
+	address, ok := labels.Labels[input]
+	if !ok {
+		if !common.IsHexAddress(input) {
+			return accounts.Account{}, fmt.Errorf("Unknown account: %s", input)
+		}
+		address = common.HexToAddress(input)
+	}
+
+	account, err := ks.Find(accounts.Account{Address: address})
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("%s: %s", input, err)
+	}
+
+	return account, nil
+}
+
+type accountResult struct {
+	Address   common.Address `json:"address"`
+	Label     string         `json:"label"`
+	File      string         `json:"file"`
+	PublicKey hexutil.Bytes  `json:"publicKey,omitempty"`
+}
+
+func newAccountResult(account accounts.Account, labels *accountLabels) *accountResult {
+	return &accountResult{
+		Address: account.Address,
+		Label:   labels.labelOf(account.Address),
+		File:    account.URL.Path,
+	}
+}
+
+func (r *accountResult) printText() {
+	fmt.Printf("Address: %s\n", r.Address.Hex())
+	if r.Label != "" {
+		fmt.Printf("Label: %s\n", r.Label)
+	}
+	fmt.Printf("File: %s\n", r.File)
+	if len(r.PublicKey) > 0 {
+		fmt.Printf("Public Key: %s\n", hexutil.Encode(r.PublicKey))
+	}
+	fmt.Println()
+}
+
+func (r *accountResult) csvHeader() []string {
+	return []string{"address", "label", "file", "publicKey"}
+}
+
+func (r *accountResult) csvRecord() []string {
+	publicKey := ""
+	if len(r.PublicKey) > 0 {
+		publicKey = hexutil.Encode(r.PublicKey)
+	}
+	return []string{r.Address.Hex(), r.Label, r.File, publicKey}
+}
+
+func newAccount(c *cli.Context) error {
+	ks, err := openKeyStore()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	label := c.String("label")
+	if _, ok := labels.Labels[label]; ok {
+		return cli.NewExitError("Label already in use: "+label, 1)
+	}
+
+	password, err := getNewPassword(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	account, err := ks.NewAccount(password)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return saveAccountLabel(labels, account, label)
+}
+
+func importAccount(c *cli.Context) error {
+	keyFile := c.Args().First()
+	if keyFile != "" && c.String("private-key-file") != "" {
+		return cli.NewExitError("Ambiguous: only a keystore file or a private key file should be provided", 1)
+	}
+
+	ks, err := openKeyStore()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	label := c.String("label")
+	if _, ok := labels.Labels[label]; ok {
+		return cli.NewExitError("Label already in use: "+label, 1)
+	}
+
+	var account accounts.Account
+
+	if keyFile != "" {
+		keyJSON, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		// a keystore file keeps its password
+		password, err := getPassword(c, "Password: ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		account, err = ks.Import(keyJSON, password, password)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	} else {
+		key, err := readPrivateKey(c)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		// wanchain accounts have a second key for private transactions,
+		// which a raw key import does not provide
+		key2, err := crypto.GenerateKey()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		password, err := getNewPassword(c)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		account, err = ks.ImportECDSA(key, key2, password)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	return saveAccountLabel(labels, account, label)
+}
+
+func saveAccountLabel(labels *accountLabels, account accounts.Account, label string) error {
+	if label != "" {
+		if err := labels.set(label, account.Address); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := labels.save(); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := printRecord(newAccountResult(account, labels)); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func listAccounts(c *cli.Context) error {
+	ks, err := openKeyStore()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	results := []*accountResult{}
+	for _, account := range ks.Accounts() {
+		results = append(results, newAccountResult(account, labels))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Label < results[j].Label
+	})
+
+	w := newRecordWriter()
+
+	for _, result := range results {
+		if err := w.write(result); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// inspectAccount decrypts an account to check its password and shows its
+// public key.
+func inspectAccount(c *cli.Context) error {
+	input := c.Args().First()
+	if input == "" {
+		return cli.NewExitError("No account label or address provided", 1)
+	}
+
+	ks, err := openKeyStore()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	account, err := findAccount(ks, labels, input)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	key, err := decryptKeyFile(c, account.URL.Path)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result := newAccountResult(account, labels)
+	result.PublicKey = crypto.FromECDSAPub(&key.PublicKey)
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
@@ -5,17 +5,19 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/spf13/viper"
 	"github.com/urfave/cli"
 
 	"github.com/jsgoyette/wanutil/contracts"
 
-	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
@@ -33,10 +35,19 @@ func getBalance(c *cli.Context) error {
 	blockNumber := big.NewInt(c.Int64("block"))
 
 	tokenSymbol := c.String("token")
-	tokenAddress := viper.GetString("contracts." + tokenSymbol)
 
-	if tokenSymbol != "" && tokenAddress == "" {
-		return cli.NewExitError("Token not found", 1)
+	var token *tokenInfo
+
+	if tokenSymbol != "" {
+		registry, err := loadTokenRegistry()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		token, err = resolveToken(client, registry, tokenSymbol)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
 	}
 
 	if blockNumber.Cmp(ZERO) == 0 {
@@ -48,18 +59,22 @@ func getBalance(c *cli.Context) error {
 		blockNumber = current
 	}
 
-	if tokenAddress != "" {
+	if token != nil {
 
 		// get token contract instance
-		ta := common.HexToAddress(tokenAddress)
-		instance, err := contracts.NewStandard(ta, client)
+		instance, err := contracts.NewStandard(token.Address, client)
 		if err != nil {
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		// check token balance on contract
+		// check token balance on contract at the block
+		opts := &bind.CallOpts{
+			BlockNumber: blockNumber,
+			Context:     context.Background(),
+		}
+
 		balance, err := instance.BalanceOf(
-			nil,
+			opts,
 			common.HexToAddress(address),
 		)
 
@@ -67,12 +82,17 @@ func getBalance(c *cli.Context) error {
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		fmt.Printf(
-			"%s balance: %s (%s)\n",
-			tokenSymbol,
-			balance.String(),
-			fromWei(balance).String(),
-		)
+		result := &balanceResult{
+			Address:   common.HexToAddress(address),
+			Token:     tokenSymbol,
+			Block:     blockNumber,
+			Balance:   balance.String(),
+			Formatted: formatUnits(balance, token.Decimals),
+		}
+
+		if err := printRecord(result); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
 
 	} else {
 
@@ -87,12 +107,16 @@ func getBalance(c *cli.Context) error {
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		fmt.Printf(
-			"Balance at block %d: %s (%s)\n",
-			blockNumber,
-			balance.String(),
-			fromWei(balance).String(),
-		)
+		result := &balanceResult{
+			Address:   common.HexToAddress(address),
+			Block:     blockNumber,
+			Balance:   balance.String(),
+			Formatted: fromWei(balance).String(),
+		}
+
+		if err := printRecord(result); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
 	}
 
 	return nil
@@ -100,19 +124,36 @@ func getBalance(c *cli.Context) error {
 
 func getTransaction(c *cli.Context) error {
 	hexHash := c.String("hash")
-	abiFileName := c.String("abi")
 
 	if hexHash == "" {
 		return cli.NewExitError("No tx hash provided", 1)
 	}
 
 	client := getWanchainConnection()
-	networkId, _ := client.NetworkID(context.Background())
 
-	hash := common.HexToHash(hexHash)
+	details, err := loadTransactionDetails(client, common.HexToHash(hexHash), c.String("abi"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := printRecord(details); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// loadTransactionDetails gets a transaction and its receipt once it is mined,
+// and decodes its input and events. An ABI file belongs to the called
+// contract.
+func loadTransactionDetails(client *wanclient.Client, hash common.Hash, abiFileName string) (*transactionDetails, error) {
+	networkId, err := getChainID(client)
+	if err != nil {
+		return nil, err
+	}
+
 	signer := types.NewEIP155Signer(networkId)
 
-	methods := map[string]AbiMethod{}
 	var from string
 
 	tx, isPending, err := client.TransactionByHash(
@@ -121,46 +162,33 @@ func getTransaction(c *cli.Context) error {
 	)
 
 	if err != nil {
-		return cli.NewExitError(err.Error(), 1)
+		return nil, err
 	}
 
 	if msg, err := tx.AsMessage(signer); err == nil {
 		from = msg.From().Hex()
 	}
 
-	printTransaction(tx, from, isPending)
+	details := &transactionDetails{
+		Transaction: newTransactionResult(tx, from, isPending),
+	}
 
-	if abiFileName != "" {
+	registry, err := loadAbiRegistry()
+	if err != nil {
+		return nil, err
+	}
 
+	if abiFileName != "" {
 		fields, err := parseAbi(abiFileName)
 		if err != nil {
-			return cli.NewExitError(err.Error(), 1)
-		}
-
-		for _, field := range fields {
-			if field.Name == "" {
-				continue
-			}
-
-			sig, sigHash := buildSignature(&field)
-			methods[sigHash] = AbiMethod{
-				AbiField:      field,
-				Signature:     sig,
-				SignatureHash: sigHash,
-			}
+			return nil, err
 		}
 
-		txData := tx.Data()
-		txDataStr := fmt.Sprintf("%x", txData)
+		registry.override(tx.To(), fields)
+	}
 
-		if txDataStr != "" {
-			for k := range methods {
-				if strings.Contains(txDataStr, k[2:10]) {
-					method := methods[k]
-					printMethod(&method, txData[4:])
-				}
-			}
-		}
+	if txData := tx.Data(); len(txData) > 0 && tx.To() != nil {
+		details.Method = registry.decodeCall(tx.To(), txData)
 	}
 
 	if !isPending {
@@ -170,26 +198,14 @@ func getTransaction(c *cli.Context) error {
 		)
 
 		if err != nil {
-			return cli.NewExitError(err.Error(), 1)
+			return nil, err
 		}
 
-		if abiFileName != "" {
-
-			for _, rlog := range receipt.Logs {
-				if len(rlog.Topics) == 0 {
-					continue
-				}
-
-				if method, ok := methods[rlog.Topics[0].String()]; ok {
-					printEvent(rlog.Address.Hex(), &method, rlog)
-				}
-			}
-		}
-
-		printReceipt(receipt)
+		details.Events = registry.decodeLogs(receipt.Logs)
+		details.Receipt = newReceiptResult(receipt)
 	}
 
-	return nil
+	return details, nil
 }
 
 func getBlock(c *cli.Context) error {
@@ -220,7 +236,9 @@ func getBlock(c *cli.Context) error {
 		}
 	}
 
-	fmt.Println(block)
+	if err := printRecord(newBlockResult(block)); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
 
 	return nil
 }
@@ -234,69 +252,183 @@ func listTransactionsFromAddress(c *cli.Context) error {
 }
 
 func scanBlockTransactions(c *cli.Context, direction string) error {
-	address := strings.ToLower(c.String("address"))
+	address := c.String("address")
 
 	if address == "" {
 		return cli.NewExitError("No address provided", 1)
 	}
 
-	client := getWanchainConnection()
-	networkId, _ := client.NetworkID(context.Background())
-	signer := types.NewEIP155Signer(networkId)
-
 	startingBlock := c.Int64("block")
+	endBlock := c.Int64("to-block")
+	lastBlocks := c.Int64("last")
+	follow := c.Bool("follow")
+	resume := c.Bool("resume")
+	force := c.Bool("force")
+
+	if lastBlocks < 0 {
+		return cli.NewExitError("The number of last blocks cannot be negative", 1)
+	}
+	if startingBlock != 0 && lastBlocks != 0 {
+		return cli.NewExitError("Ambiguous: only a starting block or a number of last blocks should be provided", 1)
+	}
+	if lastBlocks != 0 && endBlock != 0 {
+		return cli.NewExitError("Ambiguous: only an end block or a number of last blocks should be provided", 1)
+	}
+	if resume && (startingBlock != 0 || lastBlocks != 0) {
+		return cli.NewExitError("Ambiguous: a resumed scan continues from its checkpoint", 1)
+	}
+	if resume && force {
+		return cli.NewExitError("Ambiguous: only --resume or --force should be provided", 1)
+	}
 
-	if startingBlock == 0 {
-		startingBlock = 1
+	checkpointPath, err := getCheckpointPath(c.String("checkpoint"), direction, common.HexToAddress(address))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
 	}
 
+	var checkpoint *scanCheckpoint
+
+	if !resume && !force {
+		if _, err := os.Stat(checkpointPath); err == nil {
+			return cli.NewExitError(fmt.Sprintf("A checkpoint exists at %s, use --resume to continue the scan or --force to start over", checkpointPath), 1)
+		}
+	}
+
+	if resume {
+		checkpoint, err = loadCheckpoint(checkpointPath)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if checkpoint.Direction != direction || checkpoint.Address != common.HexToAddress(address) {
+			return cli.NewExitError("Checkpoint belongs to a different scan", 1)
+		}
+
+		startingBlock = checkpoint.LastBlock + 1
+		if endBlock == 0 {
+			endBlock = checkpoint.ToBlock
+		}
+	}
+
+	rpcClient := getRpcConnection()
+	client := wanclient.NewClient(rpcClient)
+
 	current, err := currentBlockNumber(client)
 	if err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	fmt.Println("Block   | Hash")
-	fmt.Println(strings.Repeat("-", 76))
+	if lastBlocks > 0 {
+		startingBlock = current.Int64() - lastBlocks + 1
+	}
 
-	for c := startingBlock; c <= current.Int64(); c++ {
+	if startingBlock < 1 {
+		startingBlock = 1
+	}
 
-		block, err := client.BlockByNumber(context.Background(), big.NewInt(c))
-		if err != nil {
+	if endBlock != 0 && endBlock < startingBlock && !resume {
+		return cli.NewExitError("End block is before the starting block", 1)
+	}
+	if endBlock > current.Int64() && !follow {
+		return cli.NewExitError(fmt.Sprintf("End block is past the current block %d", current), 1)
+	}
+
+	if checkpoint == nil {
+		checkpoint = &scanCheckpoint{
+			Direction: direction,
+			Address:   common.HexToAddress(address),
+			FromBlock: startingBlock,
+			ToBlock:   endBlock,
+			LastBlock: startingBlock - 1,
+			Matches:   []scanMatch{},
+			path:      checkpointPath,
+		}
+	}
+
+	checkpoint.ToBlock = endBlock
+
+	// the output of a followed scan is streamed, so its matches are not kept
+	// to be written again on resume
+	checkpoint.keepMatches = !follow
+
+	scanner := &blockScanner{
+		client:    rpcClient,
+		address:   common.HexToAddress(address),
+		direction: direction,
+		workers:   c.Int("workers"),
+		batchSize: c.Int("batch"),
+	}
+
+	if outputFormat == outputText {
+		fmt.Println("Block   | Hash")
+		fmt.Println(strings.Repeat("-", 76))
+	}
+
+	w := newRecordWriter()
+
+	// matches found before the scan was interrupted
+	for _, match := range checkpoint.Matches {
+		if err := w.write(match); err != nil {
+			w.close()
 			return cli.NewExitError(err.Error(), 1)
 		}
+	}
+
+	handleChunk := func(chunk *scanChunk) error {
+		for _, match := range chunk.matches {
+			if err := w.write(match); err != nil {
+				return err
+			}
+		}
+		return checkpoint.update(chunk)
+	}
+
+	nextBlock := startingBlock
+
+	for {
+		lastBlock := current.Int64()
+		if endBlock != 0 && endBlock < lastBlock {
+			lastBlock = endBlock
+		}
 
-		for _, tx := range block.Transactions() {
-			var txaddr *common.Address
-
-			if direction == "to" {
-				txaddr = tx.To()
-
-				// check receipt contract address
-				if txaddr == nil {
-
-					receipt, err := client.TransactionReceipt(
-						context.Background(),
-						tx.Hash(),
-					)
-					if err != nil {
-						return cli.NewExitError(err.Error(), 1)
-					}
-
-					txaddr = &receipt.ContractAddress
-				}
-			} else if direction == "from" {
-				if msg, err := tx.AsMessage(signer); err == nil {
-					from := msg.From()
-					txaddr = &from
-				}
+		if nextBlock <= lastBlock {
+			err = scanner.scan(context.Background(), nextBlock, lastBlock, handleChunk)
+
+			if saveErr := checkpoint.save(); saveErr != nil && err == nil {
+				err = saveErr
 			}
 
-			if txaddr != nil && address == strings.ToLower(txaddr.Hex()) {
-				fmt.Printf("%7d | %s\n", c, tx.Hash().Hex())
+			if err != nil {
+				w.close()
+				return cli.NewExitError(fmt.Sprintf("%s\nScanned up to block %d, use --resume to continue", err, checkpoint.LastBlock), 1)
 			}
+
+			nextBlock = lastBlock + 1
+		}
+
+		// in follow mode, keep polling for new blocks until the end block
+		if !follow || (endBlock != 0 && nextBlock > endBlock) {
+			break
+		}
+
+		time.Sleep(c.Duration("interval"))
+
+		current, err = currentBlockNumber(client)
+		if err != nil {
+			w.close()
+			return cli.NewExitError(err.Error(), 1)
 		}
 	}
 
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	// the scan is complete, nothing is left to resume
+	if err := checkpoint.remove(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
 	return nil
 }
 
@@ -332,8 +464,22 @@ func listAbiSignatures(c *cli.Context) error {
 	}
 	sort.Strings(keys)
 
+	w := newRecordWriter()
+
 	for _, k := range keys {
-		fmt.Printf("%s %s %s\n", k, signatures[k], inputNames[k])
+		result := &signatureResult{
+			Hash:      k,
+			Signature: signatures[k],
+			Inputs:    inputNames[k],
+		}
+
+		if err := w.write(result); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
 	}
 
 	return nil
@@ -346,48 +492,20 @@ func validateAddress(c *cli.Context) error {
 		return cli.NewExitError("No address provided", 1)
 	}
 
-	if !common.IsHexAddress(address) {
-		fmt.Println("Address is INVALID")
-	} else {
-		addr := common.HexToAddress(address)
-		fmt.Printf("Valid address: %s\n", addr.Hex())
-	}
-
-	return nil
-}
-
-func subscribe(c *cli.Context) error {
-	addrString := c.String("address")
-
-	if addrString == "" {
-		return cli.NewExitError("No address provided", 1)
+	result := &addressResult{
+		Input: address,
+		Valid: common.IsHexAddress(address),
 	}
 
-	client := getWanchainConnection()
-	startingBlock := c.Int64("block")
-
-	address := common.HexToAddress(addrString)
-	query := wanchain.FilterQuery{
-		FromBlock: big.NewInt(startingBlock),
-		Addresses: []common.Address{address},
+	if result.Valid {
+		addr := common.HexToAddress(address)
+		result.Address = &addr
 	}
 
-	logs := make(chan types.Log)
-
-	sub, err := client.SubscribeFilterLogs(context.Background(), query, logs)
-	if err != nil {
+	if err := printRecord(result); err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	for {
-		select {
-		case err := <-sub.Err():
-			return cli.NewExitError(err.Error(), 1)
-		case vLog := <-logs:
-			printLog(&vLog)
-		}
-	}
-
 	return nil
 }
 
@@ -408,15 +526,20 @@ func decodeTransaction(c *cli.Context) error {
 
 	rlp.DecodeBytes(rawtx, &tx)
 
-	client := getWanchainConnection()
-	networkId, _ := client.NetworkID(context.Background())
+	networkId, err := getChainID(nil)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
 	signer := types.NewEIP155Signer(networkId)
 
 	if msg, err := tx.AsMessage(signer); err == nil {
 		from = msg.From().Hex()
 	}
 
-	printTransaction(tx, from, false)
+	if err := printRecord(newTransactionResult(tx, from, false)); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
 
 	return nil
 }
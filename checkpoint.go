@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// how often the checkpoint is written while no new matches are found
+const checkpointInterval = 5 * time.Second
+
+// scanCheckpoint records the progress of a block scan so that it can be
+// resumed after a failure.
+type scanCheckpoint struct {
+	Direction string         `json:"direction"`
+	Address   common.Address `json:"address"`
+	FromBlock int64          `json:"fromBlock"`
+	ToBlock   int64          `json:"toBlock"`
+	LastBlock int64          `json:"lastBlock"`
+	Matches   []scanMatch    `json:"matches"`
+
+	path        string
+	savedAt     time.Time
+	keepMatches bool
+}
+
+// getCheckpointPath returns the checkpoint file for a scan. Unless a path is
+// given, each direction and address has its own file under ~/.wanutil.
+func getCheckpointPath(path string, direction string, address common.Address) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	name := "scan-" + direction + "-" + strings.ToLower(address.Hex()) + ".json"
+
+	return getWanutilPath("checkpoints", name)
+}
+
+func loadCheckpoint(path string) (*scanCheckpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &scanCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+
+	checkpoint.path = path
+
+	return checkpoint, nil
+}
+
+// save writes the checkpoint to a temporary file first, so an interrupted
+// write never leaves a truncated checkpoint behind.
+func (cp *scanCheckpoint) save() error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := cp.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	cp.savedAt = time.Now()
+
+	return os.Rename(tmp, cp.path)
+}
+
+// remove deletes the checkpoint once its scan is complete.
+func (cp *scanCheckpoint) remove() error {
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// update records a completed chunk, saving the checkpoint when matches were
+// found or the last save is older than checkpointInterval. Matches are only
+// kept when keepMatches is set.
+func (cp *scanCheckpoint) update(chunk *scanChunk) error {
+	cp.LastBlock = chunk.to
+	if cp.keepMatches {
+		cp.Matches = append(cp.Matches, chunk.matches...)
+	}
+
+	if len(chunk.matches) == 0 && time.Since(cp.savedAt) < checkpointInterval {
+		return nil
+	}
+
+	return cp.save()
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// resolveContract accepts an address, a contract name from the config or an
+// address book label.
+func resolveContract(input string) (common.Address, error) {
+	contract, ok, err := getConfigContract(input)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if ok {
+		return contract.Address, nil
+	}
+
+	address, _, err := resolveAddress(input)
+	return address, err
+}
+
+// findAbiMethod finds a function by name or by full signature, such as
+// transfer(address,uint256). Overloaded functions are told apart by their
+// number of arguments.
+func findAbiMethod(fields []AbiField, name string, argCount int) (*AbiMethod, error) {
+	matches := []*AbiMethod{}
+
+	for _, field := range fields {
+		if field.Type != "" && field.Type != "function" {
+			continue
+		}
+
+		sig, sigHash := buildSignature(&field)
+
+		if strings.Contains(name, "(") {
+			if sig != strings.Replace(name, " ", "", -1) {
+				continue
+			}
+		} else if field.Name != name || len(field.Inputs) != argCount {
+			continue
+		}
+
+		matches = append(matches, &AbiMethod{
+			AbiField:      field,
+			Signature:     sig,
+			SignatureHash: sigHash,
+		})
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("No method %s with %d arguments in ABI", name, argCount)
+	}
+
+	if len(matches) > 1 {
+		sigs := make([]string, len(matches))
+		for i, match := range matches {
+			sigs[i] = match.Signature
+		}
+		return nil, fmt.Errorf("Ambiguous method %s, use one of: %s", name, strings.Join(sigs, ", "))
+	}
+
+	return matches[0], nil
+}
+
+// packMethodCall encodes the call data of a method from command line
+// arguments.
+func packMethodCall(method *AbiMethod, args []string) ([]byte, error) {
+	values, err := parseAbiArguments(method.Inputs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodeAbiValues(getArgumentTypes(method.Inputs), values)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := crypto.Keccak256([]byte(method.Signature))[:4]
+
+	return append(selector, encoded...), nil
+}
+
+// contractCall is a method call encoded from the command line.
+type contractCall struct {
+	address  common.Address
+	registry *abiRegistry
+	method   *AbiMethod
+	data     []byte
+}
+
+// loadContractCall reads the contract, method and arguments shared by the
+// commands calling contract methods. The ABI is the one given with --abi or
+// else the one bound to the contract in the ABI registry.
+func loadContractCall(c *cli.Context) (*contractCall, error) {
+	contract := c.String("address")
+
+	if contract == "" {
+		return nil, errors.New("No contract address provided")
+	}
+	if c.NArg() == 0 {
+		return nil, errors.New("No method provided")
+	}
+
+	address, err := resolveContract(contract)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := loadAbiRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := registry.contractAbi(address)
+
+	if abiFileName := c.String("abi"); abiFileName != "" {
+		fields, err = parseAbi(abiFileName)
+		if err != nil {
+			return nil, err
+		}
+
+		registry.override(&address, fields)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("No ABI known for %s, use --abi", address.Hex())
+	}
+
+	args := c.Args()[1:]
+
+	method, err := findAbiMethod(fields, c.Args().First(), len(args))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := packMethodCall(method, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contractCall{
+		address:  address,
+		registry: registry,
+		method:   method,
+		data:     data,
+	}, nil
+}
+
+// callResult holds the values returned by a contract call.
+type callResult struct {
+	Contract  common.Address `json:"contract"`
+	Signature string         `json:"signature"`
+	Block     *big.Int       `json:"block,omitempty"`
+	Outputs   []decodedValue `json:"outputs"`
+	Raw       hexutil.Bytes  `json:"raw"`
+
+	method *AbiMethod
+	values []interface{}
+}
+
+func (r *callResult) printText() {
+	fmt.Println("Contract:", r.Contract.Hex())
+	fmt.Println("Method:", r.Signature)
+	if r.Block != nil {
+		fmt.Println("Block:", r.Block)
+	}
+	fmt.Println("Outputs:", getInputNamesString(r.method.Outputs))
+	printValues(r.method.Outputs, r.values)
+	fmt.Println()
+}
+
+func (r *callResult) csvHeader() []string {
+	header := []string{"contract", "signature", "block"}
+	for i, output := range r.method.Outputs {
+		name := output.Name
+		if name == "" {
+			name = fmt.Sprintf("output%d", i)
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+func (r *callResult) csvRecord() []string {
+	block := ""
+	if r.Block != nil {
+		block = r.Block.String()
+	}
+
+	record := []string{r.Contract.Hex(), r.Signature, block}
+	for i, output := range r.method.Outputs {
+		record = append(record, formatAbiValue(output.Type, r.values[i]))
+	}
+	return record
+}
+
+func callContract(c *cli.Context) error {
+	call, err := loadContractCall(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	address, method := call.address, call.method
+
+	var blockNumber *big.Int
+	if c.IsSet("block") {
+		blockNumber = big.NewInt(c.Int64("block"))
+	}
+
+	client := getWanchainConnection()
+
+	msg := wanchain.CallMsg{To: &address, Data: call.data}
+
+	output, err := client.CallContract(context.Background(), msg, blockNumber)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if len(output) == 0 && len(method.Outputs) > 0 {
+		return cli.NewExitError("Empty result, is "+address.Hex()+" a contract?", 1)
+	}
+
+	values, err := decodeAbiValues(getArgumentTypes(method.Outputs), output)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result := &callResult{
+		Contract:  address,
+		Signature: method.Signature,
+		Block:     blockNumber,
+		Outputs:   newDecodedValues(method.Outputs, values),
+		Raw:       output,
+		method:    method,
+		values:    values,
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// sendMethod signs and sends a transaction calling a contract method, waits
+// for the receipt and decodes its logs with the known ABIs.
+func sendMethod(c *cli.Context) error {
+	call, err := loadContractCall(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if call.method.isReadOnly() {
+		return cli.NewExitError(call.method.Signature+" does not modify the state, use the call command", 1)
+	}
+
+	key, err := loadPrivateKey(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	params, err := getTxParams(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	params.from = crypto.PubkeyToAddress(key.PublicKey)
+	params.to = &call.address
+	params.data = call.data
+
+	if value := c.String("value"); value != "" {
+		params.value, err = parseUnits(value, 18)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if params.value.Sign() > 0 && call.method.isNonPayable() {
+			return cli.NewExitError(call.method.Signature+" is not payable, a value cannot be sent", 1)
+		}
+	}
+
+	client := getWanchainConnection()
+
+	if err := params.fill(client); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	chainID, err := getSigningChainID(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	tx, err := params.sign(key, chainID)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result, err := newSignedTxResult(tx, params.from)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result.Sent = true
+
+	receipt, waitErr := result.waitForReceipt(client, tx)
+	if receipt != nil {
+		result.Events = call.registry.decodeLogs(receipt.Logs)
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if waitErr != nil {
+		return cli.NewExitError(waitErr.Error(), 1)
+	}
+
+	return nil
+}
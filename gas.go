@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// transferGas is the gas of a plain WAN transfer, used when no call is given.
+const transferGas = 21000
+
+const (
+	// maxGasBlocks caps the number of blocks sampled for gas prices.
+	maxGasBlocks = 500
+
+	// gasBatchSize is the number of blocks requested per JSON-RPC batch.
+	gasBatchSize = 50
+)
+
+// gasPercentiles are the percentiles of the sampled gas prices offered as
+// options besides the minimum and the node suggestion.
+var gasPercentiles = []int{25, 50, 75, 90}
+
+// gasOption is a gas price with the fee it costs for the gas of the call.
+type gasOption struct {
+	Option   string `json:"option"`
+	GasPrice string `json:"gasPrice"`
+	Gas      string `json:"gas"`
+	Fee      string `json:"fee"`
+}
+
+func newGasOption(option string, gasPrice, gas *big.Int) *gasOption {
+	return &gasOption{
+		Option:   option,
+		GasPrice: gasPrice.String(),
+		Gas:      gas.String(),
+		Fee:      formatUnits(new(big.Int).Mul(gasPrice, gas), 18),
+	}
+}
+
+func (o *gasOption) printText() {
+	gasPrice, _ := new(big.Int).SetString(o.GasPrice, 10)
+	fmt.Printf("%-9s | %14s gwin | %s WAN\n", o.Option, formatUnits(gasPrice, 9), o.Fee)
+}
+
+func (o *gasOption) csvHeader() []string {
+	return []string{"option", "gasPrice", "gas", "fee"}
+}
+
+func (o *gasOption) csvRecord() []string {
+	return []string{o.Option, o.GasPrice, o.Gas, o.Fee}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []*big.Int, p int) *big.Int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// gasBlock holds the gas prices of an eth_getBlockByNumber response.
+type gasBlock struct {
+	Transactions []struct {
+		GasPrice *hexutil.Big
+	}
+}
+
+// sampleGasPrices returns the gas prices of the transactions in the last
+// blocks, sorted. The blocks are requested in JSON-RPC batches.
+func sampleGasPrices(rpcClient *rpc.Client, head *big.Int, blocks int64) ([]*big.Int, error) {
+	if blocks > head.Int64()+1 {
+		blocks = head.Int64() + 1
+	}
+
+	results := make([]*gasBlock, blocks)
+	elems := make([]rpc.BatchElem, blocks)
+
+	for i := range elems {
+		number := new(big.Int).Sub(head, big.NewInt(int64(i)))
+
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(number), true},
+			Result: &results[i],
+		}
+	}
+
+	if err := batchCallRPC(context.Background(), rpcClient, elems, gasBatchSize); err != nil {
+		return nil, err
+	}
+
+	prices := []*big.Int{}
+
+	for _, block := range results {
+		if block == nil {
+			continue
+		}
+
+		for _, tx := range block.Transactions {
+			if tx.GasPrice != nil {
+				prices = append(prices, tx.GasPrice.ToInt())
+			}
+		}
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Cmp(prices[j]) < 0
+	})
+
+	return prices, nil
+}
+
+// buildGasCall reads the call to estimate: a contract method with its
+// arguments, or a transaction given with --to, --value and --data. Without
+// either it is nil.
+func buildGasCall(c *cli.Context) (*wanchain.CallMsg, error) {
+	msg := &wanchain.CallMsg{Value: new(big.Int)}
+
+	if from := c.String("from"); from != "" {
+		address, _, err := resolveAddress(from)
+		if err != nil {
+			return nil, err
+		}
+		msg.From = address
+	}
+
+	if value := c.String("value"); value != "" {
+		amount, err := parseUnits(value, 18)
+		if err != nil {
+			return nil, err
+		}
+		msg.Value = amount
+	}
+
+	if c.NArg() > 0 {
+		call, err := loadContractCall(c)
+		if err != nil {
+			return nil, err
+		}
+
+		msg.To = &call.address
+		msg.Data = call.data
+
+		return msg, nil
+	}
+
+	if to := c.String("to"); to != "" {
+		address, err := resolveContract(to)
+		if err != nil {
+			return nil, err
+		}
+		msg.To = &address
+	}
+
+	if data := c.String("data"); data != "" {
+		input, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid data: %s", err)
+		}
+		msg.Data = input
+	}
+
+	if msg.To == nil && len(msg.Data) == 0 {
+		return nil, nil
+	}
+
+	return msg, nil
+}
+
+// adviseGas estimates the gas of a call and prints the fee it costs at the
+// gas price suggested by the node and at the prices paid in recent blocks.
+func adviseGas(c *cli.Context) error {
+	msg, err := buildGasCall(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	rpcClient := getRpcConnection()
+	client := wanclient.NewClient(rpcClient)
+	ctx := context.Background()
+
+	gas := big.NewInt(transferGas)
+
+	switch {
+	case c.IsSet("gas"):
+		gas = big.NewInt(c.Int64("gas"))
+
+	case msg != nil:
+		gas, err = client.EstimateGas(ctx, *msg)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("gas estimation failed: %s", err), 1)
+		}
+		fmt.Fprintf(os.Stderr, "Estimated gas: %s\n", gas)
+
+	default:
+		fmt.Fprintf(os.Stderr, "No call given, using the gas of a transfer: %s\n", gas)
+	}
+
+	suggested, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	blocks := c.Int64("blocks")
+	if blocks < 1 {
+		blocks = 1
+	}
+	if blocks > maxGasBlocks {
+		fmt.Fprintf(os.Stderr, "Sampling at most %d blocks\n", maxGasBlocks)
+		blocks = maxGasBlocks
+	}
+
+	head, err := currentBlockNumber(client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	prices, err := sampleGasPrices(rpcClient, head, blocks)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Sampled %d transactions in the last %d blocks\n", len(prices), blocks)
+
+	options := []*gasOption{newGasOption("suggested", suggested, gas)}
+
+	if len(prices) > 0 {
+		options = append(options, newGasOption("min", prices[0], gas))
+
+		for _, p := range gasPercentiles {
+			name := fmt.Sprintf("p%d", p)
+			if p == 50 {
+				name = "median"
+			}
+			options = append(options, newGasOption(name, percentile(prices, p), gas))
+		}
+	}
+
+	w := newRecordWriter()
+
+	for _, option := range options {
+		if err := w.write(option); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
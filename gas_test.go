@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	prices := func(values ...int64) []*big.Int {
+		sorted := make([]*big.Int, len(values))
+		for i, value := range values {
+			sorted[i] = big.NewInt(value)
+		}
+		return sorted
+	}
+
+	tests := []struct {
+		sorted []*big.Int
+		p      int
+		want   int64
+	}{
+		{prices(7), 25, 7},
+		{prices(7), 90, 7},
+		{prices(1, 2), 50, 1},
+		{prices(1, 2), 51, 2},
+		{prices(1, 2, 3, 4), 25, 1},
+		{prices(1, 2, 3, 4), 50, 2},
+		{prices(1, 2, 3, 4), 75, 3},
+		{prices(1, 2, 3, 4), 90, 4},
+		{prices(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 90, 9},
+		{prices(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 100, 10},
+		{prices(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0, 1},
+	}
+
+	for _, test := range tests {
+		if got := percentile(test.sorted, test.p); got.Int64() != test.want {
+			t.Errorf("p%d of %v: got %s, want %d", test.p, test.sorted, got, test.want)
+		}
+	}
+}
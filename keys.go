@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// loadPrivateKey loads the signing key given with --keystore (encrypted key
+// file), --from (wanutil account label or address) or --private-key-file
+// (raw hex). Without any of them a raw key is read from stdin.
+func loadPrivateKey(c *cli.Context) (*ecdsa.PrivateKey, error) {
+	if c.String("private-key-file") != "" {
+		return readPrivateKey(c)
+	}
+
+	if keyFile := c.String("keystore"); keyFile != "" {
+		return decryptKeyFile(c, keyFile)
+	}
+
+	from := c.String("from")
+	if from == "" {
+		return readPrivateKey(c)
+	}
+
+	ks, err := openKeyStore()
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := findAccount(ks, labels, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptKeyFile(c, account.URL.Path)
+}
+
+func decryptKeyFile(c *cli.Context, keyFile string) (*ecdsa.PrivateKey, error) {
+	keyJSON, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := getPassword(c, "Password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", keyFile, err)
+	}
+
+	return key.PrivateKey, nil
+}
+
+// getPassword reads the keystore password from --password-file, the
+// WANUTIL_PASSWORD environment variable or the terminal, in that order.
+func getPassword(c *cli.Context, prompt string) (string, error) {
+	if passwordFile := c.String("password-file"); passwordFile != "" {
+		data, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if password, ok := os.LookupEnv("WANUTIL_PASSWORD"); ok {
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.New("No password provided, use --password-file or WANUTIL_PASSWORD")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
+
+// readPrivateKey reads a raw private key as hex from --private-key-file, or
+// from stdin, where a terminal does not echo it. Keys are never taken as
+// arguments, which end up in the shell history and the process list.
+func readPrivateKey(c *cli.Context) (*ecdsa.PrivateKey, error) {
+	var data []byte
+	var err error
+
+	fd := int(os.Stdin.Fd())
+
+	switch keyFile := c.String("private-key-file"); {
+	case keyFile != "":
+		data, err = ioutil.ReadFile(keyFile)
+
+	case terminal.IsTerminal(fd):
+		fmt.Fprint(os.Stderr, "Private key: ")
+		data, err = terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+
+	default:
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		return nil, errors.New("No private key provided, use --from, --keystore or --private-key-file, or give the key on stdin")
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, errors.New("Not a private key as hex")
+	}
+
+	return key, nil
+}
+
+// getNewPassword reads the password for a new key. A password typed on the
+// terminal has to be repeated.
+func getNewPassword(c *cli.Context) (string, error) {
+	password, err := getPassword(c, "New password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := os.LookupEnv("WANUTIL_PASSWORD"); ok || c.String("password-file") != "" {
+		return password, nil
+	}
+
+	confirm, err := getPassword(c, "Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if password != confirm {
+		return "", errors.New("Passwords do not match")
+	}
+
+	return password, nil
+}
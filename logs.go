@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+)
+
+// defaultLogBlocks is the number of recent blocks queried when no range is
+// given.
+const defaultLogBlocks = 10000
+
+// logEvent is the event of a log filter with the types of its topics after
+// the event topic.
+type logEvent struct {
+	topic      common.Hash
+	event      *AbiMethod
+	topicTypes []*AbiType
+}
+
+// findEvent resolves an event given as a topic hash, a signature such as
+// Transfer(address,address,uint256) or the name of an event in the ABIs of
+// the addresses or in all known ABIs.
+func (r *abiRegistry) findEvent(addresses []common.Address, input string) (*logEvent, error) {
+	indexes := []*abiIndex{}
+	for _, address := range addresses {
+		if index, ok := r.contracts[address]; ok {
+			indexes = append(indexes, index)
+		}
+	}
+	indexes = append(indexes, r.all)
+
+	if len(input) == 66 && strings.HasPrefix(input, "0x") {
+		if _, err := hex.DecodeString(input[2:]); err != nil {
+			return nil, fmt.Errorf("Invalid event topic: %s", input)
+		}
+
+		topic := common.HexToHash(input)
+
+		event, err := findTopicEvent(indexes, topic)
+		if err != nil || event != nil {
+			return event, err
+		}
+
+		return &logEvent{topic: topic}, nil
+	}
+
+	if strings.Contains(input, "(") {
+		method, err := parseSignature(input)
+		if err != nil {
+			return nil, err
+		}
+
+		topic := common.HexToHash(method.SignatureHash)
+
+		// prefer the ABI, which knows the indexed inputs
+		event, err := findTopicEvent(indexes, topic)
+		if err != nil || event != nil {
+			return event, err
+		}
+
+		// without an ABI the leading inputs are assumed to be indexed,
+		// and logs are decoded the same way by the signature database
+		if err := r.signatures.addSignature(method.Signature, method.SignatureHash); err != nil {
+			return nil, err
+		}
+
+		return &logEvent{topic: topic, event: method, topicTypes: getArgumentTypes(method.Inputs)}, nil
+	}
+
+	for _, index := range indexes {
+		topics := map[common.Hash]string{}
+
+		for topic, events := range index.events {
+			for _, event := range events {
+				if event.Name == input {
+					topics[topic] = event.Signature
+				}
+			}
+		}
+
+		if len(topics) == 1 {
+			for topic := range topics {
+				return findTopicEvent([]*abiIndex{index}, topic)
+			}
+		}
+
+		if len(topics) > 1 {
+			sigs := []string{}
+			for _, sig := range topics {
+				sigs = append(sigs, sig)
+			}
+			sort.Strings(sigs)
+			return nil, fmt.Errorf("Ambiguous event %s, use one of: %s", input, strings.Join(sigs, ", "))
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown event %s, give its signature such as Transfer(address,address,uint256)", input)
+}
+
+// findTopicEvent returns the event of a topic from the first index which
+// knows it, or nil when none does. The same event may be declared with
+// different indexed inputs, such as ERC20 and ERC721 Transfer, in which case
+// the topic values cannot be encoded and an error is returned.
+func findTopicEvent(indexes []*abiIndex, topic common.Hash) (*logEvent, error) {
+	for _, index := range indexes {
+		events := index.events[topic]
+		if len(events) == 0 {
+			continue
+		}
+
+		for _, event := range events[1:] {
+			if indexedInputs(event) != indexedInputs(events[0]) {
+				return nil, fmt.Errorf("Ambiguous event %s, declared with different indexed inputs, use -a with a contract whose ABI declares it", event.Signature)
+			}
+		}
+
+		return newLogEvent(events[0]), nil
+	}
+
+	return nil, nil
+}
+
+// indexedInputs describes which inputs of an event are indexed.
+func indexedInputs(event *AbiMethod) string {
+	flags := make([]byte, len(event.Inputs))
+	for i, input := range event.Inputs {
+		flags[i] = '0'
+		if input.Indexed {
+			flags[i] = '1'
+		}
+	}
+	return string(flags)
+}
+
+func newLogEvent(event *AbiMethod) *logEvent {
+	topicTypes := []*AbiType{}
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			topicTypes = append(topicTypes, input.Type)
+		}
+	}
+
+	return &logEvent{
+		topic:      common.HexToHash(event.SignatureHash),
+		event:      event,
+		topicTypes: topicTypes,
+	}
+}
+
+// topicValue converts a filter value to a topic. Values of indexed inputs of
+// a known type are parsed as that type; strings and bytes are hashed.
+// Without a type the value must be a 32-byte hash, an address or an integer.
+func topicValue(t *AbiType, input string) (common.Hash, error) {
+	if len(input) == 66 && strings.HasPrefix(input, "0x") {
+		if _, err := hex.DecodeString(input[2:]); err == nil {
+			return common.HexToHash(input), nil
+		}
+	}
+
+	if t == nil {
+		if address, _, err := resolveAddress(input); err == nil {
+			return common.BytesToHash(address.Bytes()), nil
+		}
+
+		if value, ok := parseBig256(input); ok {
+			return common.BigToHash(value), nil
+		}
+
+		return common.Hash{}, fmt.Errorf("Invalid topic value %s, expected a 32-byte hash, an address or an integer", input)
+	}
+
+	switch t.T {
+	case stringTy:
+		return crypto.Keccak256Hash([]byte(input)), nil
+
+	case bytesTy:
+		value, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("invalid %s value: %s", t, input)
+		}
+		return crypto.Keccak256Hash(value), nil
+	}
+
+	if t.isHashedTopic() {
+		return common.Hash{}, fmt.Errorf("Indexed %s values are hashed, give the topic hash", t)
+	}
+
+	value, err := parseAbiValue(t, input)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	encoded, err := t.encode(value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return common.BytesToHash(encoded), nil
+}
+
+// buildLogQuery builds a filter query from the addresses, the event and the
+// indexed values of the command line. Each topic position matches any of
+// its values.
+func buildLogQuery(c *cli.Context, registry *abiRegistry) (wanchain.FilterQuery, error) {
+	query := wanchain.FilterQuery{}
+
+	for _, input := range c.StringSlice("address") {
+		address, err := resolveContract(input)
+		if err != nil {
+			return query, err
+		}
+		query.Addresses = append(query.Addresses, address)
+	}
+
+	var event *logEvent
+	topics := [][]common.Hash{nil}
+
+	if input := c.String("event"); input != "" {
+		var err error
+
+		event, err = registry.findEvent(query.Addresses, input)
+		if err != nil {
+			return query, err
+		}
+
+		topics[0] = []common.Hash{event.topic}
+	}
+
+	for i := 1; i <= 3; i++ {
+		values := c.StringSlice(fmt.Sprintf("topic%d", i))
+
+		var t *AbiType
+		if event != nil && event.event != nil {
+			if i > len(event.topicTypes) && len(values) > 0 {
+				return query, fmt.Errorf("%s has only %d indexed inputs", event.event.Signature, len(event.topicTypes))
+			}
+			if i <= len(event.topicTypes) {
+				t = event.topicTypes[i-1]
+			}
+		}
+
+		position := []common.Hash{}
+		for _, value := range values {
+			topic, err := topicValue(t, value)
+			if err != nil {
+				return query, fmt.Errorf("topic%d: %s", i, err)
+			}
+			position = append(position, topic)
+		}
+
+		topics = append(topics, position)
+	}
+
+	// trailing empty positions match anything anyway
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+
+	query.Topics = topics
+
+	return query, nil
+}
+
+// logRangeErrors are the errors nodes return when a log query has too many
+// results or spans too many blocks.
+var logRangeErrors = []string{
+	"query returned more than",
+	"log response size exceeded",
+	"block range is too wide",
+	"block range too wide",
+	"block range is too large",
+	"block range too large",
+	"exceed maximum block range",
+	"requested too many blocks",
+}
+
+func isLogRangeError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, part := range logRangeErrors {
+		if strings.Contains(msg, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLogPages runs the query over the block range in pages. When the node
+// refuses a page because it has too many results or spans too many blocks,
+// the page is halved and retried. Other errors fail the query.
+func filterLogPages(client *wanclient.Client, query wanchain.FilterQuery, from, to, pageSize uint64, handle func([]types.Log) error) error {
+	if pageSize < 1 {
+		pageSize = 1
+	}
+
+	for from <= to {
+		end := from + pageSize - 1
+		if end > to {
+			end = to
+		}
+
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = new(big.Int).SetUint64(end)
+
+		logs, err := client.FilterLogs(context.Background(), query)
+		if err != nil {
+			if end > from && isLogRangeError(err) {
+				pageSize = (end - from + 1) / 2
+				continue
+			}
+			return fmt.Errorf("blocks %d-%d: %s", from, end, err)
+		}
+
+		if err := handle(logs); err != nil {
+			return err
+		}
+
+		from = end + 1
+	}
+
+	return nil
+}
+
+func getLogs(c *cli.Context) error {
+	registry, err := loadAbiRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	query, err := buildLogQuery(c, registry)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if len(query.Addresses) == 0 && len(query.Topics) == 0 {
+		return cli.NewExitError("No address or event provided", 1)
+	}
+
+	client := getWanchainConnection()
+
+	current, err := currentBlockNumber(client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	from := c.Int64("block")
+	to := c.Int64("to-block")
+	last := c.Int64("last")
+
+	if to == 0 {
+		to = current.Int64()
+	}
+
+	switch {
+	case last < 0:
+		return cli.NewExitError("The number of last blocks cannot be negative", 1)
+
+	case c.IsSet("block") && last != 0:
+		return cli.NewExitError("Ambiguous: only a starting block or a number of last blocks should be provided", 1)
+
+	case !c.IsSet("block"):
+		// scanning from the genesis block is only done when asked for
+		if last == 0 {
+			last = defaultLogBlocks
+			fmt.Fprintf(os.Stderr, "Querying the last %d blocks, use -b or -last for another range\n", last)
+		}
+		from = to - last + 1
+	}
+
+	if from < 0 {
+		from = 0
+	}
+
+	if to < from {
+		return cli.NewExitError("End block is before the starting block", 1)
+	}
+	if to > current.Int64() {
+		return cli.NewExitError(fmt.Sprintf("End block is past the current block %d", current), 1)
+	}
+
+	w := newRecordWriter()
+
+	err = filterLogPages(client, query, uint64(from), uint64(to), uint64(c.Int64("page")), func(logs []types.Log) error {
+		for i := range logs {
+			result := newLogResult(&logs[i])
+			if event := registry.event(&logs[i]); event != nil {
+				result.Event = newDecodedEvent(event, &logs[i])
+			}
+
+			if err := w.write(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if closeErr := w.close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
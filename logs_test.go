@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsLogRangeError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"query returned more than 10000 results", true},
+		{"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range", true},
+		{"block range is too wide", true},
+		{"eth_getLogs block range too large, range: 10001, max: 5000", true},
+		{"exceed maximum block range: 5000", true},
+		{"requested too many blocks from 0 to 20000, maximum is set to 10000", true},
+		{"rate limit exceeded", false},
+		{"exceeds block gas limit", false},
+		{"request size limit reached", false},
+		{"too many requests", false},
+		{"connection refused", false},
+		{"context deadline exceeded", false},
+	}
+
+	for _, test := range tests {
+		if got := isLogRangeError(errors.New(test.err)); got != test.want {
+			t.Errorf("%q: got %t, want %t", test.err, got, test.want)
+		}
+	}
+}
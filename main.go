@@ -36,7 +36,7 @@ func main() {
 	app.UsageText = "wanutil <command> [options]"
 	app.Version = "0.0.1"
 
-	app.Flags = []cli.Flag{}
+	app.Flags = []cli.Flag{networkFlag, outputFlag}
 	app.Before = beforeApp
 	app.Commands = commands
 
@@ -47,5 +47,63 @@ func main() {
 }
 
 func beforeApp(c *cli.Context) error {
+	switch format := c.GlobalString("output"); format {
+	case outputText, outputJSON, outputNDJSON, outputCSV:
+		outputFormat = format
+	default:
+		return cli.NewExitError("Unknown output format: "+format, 1)
+	}
+
+	if err := selectNetwork(c.GlobalString("network")); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// activeNetwork is the name of the selected network profile. It is empty
+// when the config file has no profiles.
+var activeNetwork string
+
+// networkSettings are the settings which belong to a network. A selected
+// profile must give its own nodeuri, and never inherits the others from the
+// top level of the config, where they may be meant for another network.
+var networkSettings = map[string]interface{}{
+	"chainid":   0,
+	"contracts": map[string]interface{}{},
+	"abidir":    "",
+}
+
+// selectNetwork applies a profile from the networks section of the config.
+// The profile settings override the top level ones, so nodeuri, chainid,
+// contracts and abidir are read the same way with or without profiles.
+func selectNetwork(name string) error {
+	if name == "" {
+		name = viper.GetString("network")
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	profile := viper.Sub("networks." + name)
+	if profile == nil {
+		return fmt.Errorf("Network profile not found: %s", name)
+	}
+
+	if profile.GetString("nodeuri") == "" {
+		return fmt.Errorf("Network profile %s has no nodeuri", name)
+	}
+
+	for key, value := range networkSettings {
+		viper.Set(key, value)
+	}
+
+	for key, value := range profile.AllSettings() {
+		viper.Set(key, value)
+	}
+
+	activeNetwork = name
+
 	return nil
 }
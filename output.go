@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputCSV    = "csv"
+)
+
+// outputFormat is set from the global --output flag
+var outputFormat = outputText
+
+// record is a structured command result. JSON output is generated from the
+// struct fields, the text and CSV output from its methods.
+type record interface {
+	printText()
+	csvHeader() []string
+	csvRecord() []string
+}
+
+// recordWriter writes a stream of records in the selected output format.
+// JSON output is an array of records, NDJSON one record per line and CSV a
+// header row followed by one row per record.
+type recordWriter struct {
+	format string
+	csv    *csv.Writer
+	count  int
+}
+
+func newRecordWriter() *recordWriter {
+	return &recordWriter{
+		format: outputFormat,
+		csv:    csv.NewWriter(os.Stdout),
+	}
+}
+
+func (w *recordWriter) write(r record) error {
+	defer func() { w.count++ }()
+
+	switch w.format {
+	case outputJSON:
+		data, err := json.MarshalIndent(r, "  ", "  ")
+		if err != nil {
+			return err
+		}
+
+		if w.count == 0 {
+			fmt.Print("[\n  ")
+		} else {
+			fmt.Print(",\n  ")
+		}
+
+		_, err = os.Stdout.Write(data)
+		return err
+
+	case outputNDJSON:
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Println(string(data))
+		return err
+
+	case outputCSV:
+		if w.count == 0 {
+			if err := w.csv.Write(r.csvHeader()); err != nil {
+				return err
+			}
+		}
+
+		if err := w.csv.Write(r.csvRecord()); err != nil {
+			return err
+		}
+
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+
+	r.printText()
+	return nil
+}
+
+// close finishes the output, closing the JSON array.
+func (w *recordWriter) close() error {
+	if w.format == outputJSON {
+		if w.count == 0 {
+			fmt.Println("[]")
+		} else {
+			fmt.Println("\n]")
+		}
+	}
+
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// printRecord writes a command's single result. Unlike recordWriter, JSON
+// output is the bare object.
+func printRecord(r record) error {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Println(string(data))
+		return err
+	}
+
+	w := newRecordWriter()
+	if err := w.write(r); err != nil {
+		return err
+	}
+
+	return w.close()
+}
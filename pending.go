@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// rpcPendingTx is a transaction as returned by eth_getTransactionByHash and
+// txpool_content, which include the sender.
+type rpcPendingTx struct {
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Value    *hexutil.Big    `json:"value"`
+	Gas      *hexutil.Big    `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Input    hexutil.Bytes   `json:"input"`
+}
+
+type pendingTxResult struct {
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    uint64          `json:"nonce"`
+	Value    string          `json:"value"`
+	Gas      string          `json:"gas"`
+	GasPrice string          `json:"gasPrice"`
+	Data     hexutil.Bytes   `json:"data"`
+	Method   *decodedCall    `json:"method,omitempty"`
+}
+
+func newPendingTxResult(tx *rpcPendingTx) *pendingTxResult {
+	return &pendingTxResult{
+		Hash:     tx.Hash,
+		From:     tx.From,
+		To:       tx.To,
+		Nonce:    uint64(tx.Nonce),
+		Value:    bigString(tx.Value),
+		Gas:      bigString(tx.Gas),
+		GasPrice: bigString(tx.GasPrice),
+		Data:     tx.Input,
+	}
+}
+
+func bigString(value *hexutil.Big) string {
+	if value == nil {
+		return "0"
+	}
+	return value.ToInt().String()
+}
+
+func (r *pendingTxResult) printText() {
+	fmt.Printf("Hash: %s\n", r.Hash.Hex())
+	fmt.Printf("From: %s\n", r.From.Hex())
+	if r.To != nil {
+		fmt.Printf("To: %s\n", r.To.Hex())
+	}
+	fmt.Printf("Nonce: %d\n", r.Nonce)
+	fmt.Printf("Value: %s\n", r.Value)
+	fmt.Printf("Gas: %s\n", r.Gas)
+	fmt.Printf("Gas Price: %s\n", r.GasPrice)
+
+	if r.Method != nil {
+		r.Method.printText()
+	} else {
+		fmt.Println()
+	}
+}
+
+func (r *pendingTxResult) csvHeader() []string {
+	return []string{"hash", "from", "to", "nonce", "value", "gas", "gasPrice", "selector", "method"}
+}
+
+func (r *pendingTxResult) csvRecord() []string {
+	to, selector, method := "", "", ""
+
+	if r.To != nil {
+		to = r.To.Hex()
+	}
+	if len(r.Data) >= 4 {
+		selector = hexutil.Encode(r.Data[:4])
+	}
+	if r.Method != nil {
+		method = r.Method.Name
+	}
+
+	return []string{
+		r.Hash.Hex(),
+		r.From.Hex(),
+		to,
+		strconv.FormatUint(r.Nonce, 10),
+		r.Value,
+		r.Gas,
+		r.GasPrice,
+		selector,
+		method,
+	}
+}
+
+// pendingFilter selects pending transactions by sender, recipient and method
+// selector. Each filter matches any of its values and is ignored when empty.
+type pendingFilter struct {
+	from      map[common.Address]bool
+	to        map[common.Address]bool
+	selectors map[string]bool
+}
+
+func (f *pendingFilter) matches(tx *rpcPendingTx) bool {
+	if len(f.from) > 0 && !f.from[tx.From] {
+		return false
+	}
+
+	if len(f.to) > 0 && (tx.To == nil || !f.to[*tx.To]) {
+		return false
+	}
+
+	if len(f.selectors) > 0 && (len(tx.Input) < 4 || !f.selectors[hexutil.Encode(tx.Input[:4])]) {
+		return false
+	}
+
+	return true
+}
+
+// findSelectors resolves a method given as a 4-byte selector, a signature
+// such as transfer(address,uint256) or the name of methods in known ABIs.
+func (r *abiRegistry) findSelectors(input string) ([]string, error) {
+	if len(input) == 10 && strings.HasPrefix(input, "0x") {
+		if _, err := hex.DecodeString(input[2:]); err != nil {
+			return nil, fmt.Errorf("Invalid method selector: %s", input)
+		}
+		return []string{strings.ToLower(input)}, nil
+	}
+
+	if strings.Contains(input, "(") {
+		method, err := parseSignature(input)
+		if err != nil {
+			return nil, err
+		}
+		return []string{method.SignatureHash[:10]}, nil
+	}
+
+	selectors := []string{}
+	for selector, method := range r.all.methods {
+		if method.Name == input {
+			selectors = append(selectors, hexutil.Encode(selector[:]))
+		}
+	}
+
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("Unknown method %s, give its selector or signature such as transfer(address,uint256)", input)
+	}
+
+	return selectors, nil
+}
+
+func buildPendingFilter(c *cli.Context, registry *abiRegistry) (*pendingFilter, error) {
+	filter := &pendingFilter{
+		from:      map[common.Address]bool{},
+		to:        map[common.Address]bool{},
+		selectors: map[string]bool{},
+	}
+
+	for _, input := range c.StringSlice("from") {
+		address, err := resolveContract(input)
+		if err != nil {
+			return nil, err
+		}
+		filter.from[address] = true
+	}
+
+	for _, input := range c.StringSlice("to") {
+		address, err := resolveContract(input)
+		if err != nil {
+			return nil, err
+		}
+		filter.to[address] = true
+	}
+
+	for _, input := range c.StringSlice("method") {
+		selectors, err := registry.findSelectors(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, selector := range selectors {
+			filter.selectors[selector] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// pendingWatcher follows the transactions entering the transaction pool of
+// the node. It subscribes to newPendingTransactions, and over transports
+// without subscriptions polls txpool_content instead.
+type pendingWatcher struct {
+	interval time.Duration
+	handle   func(*rpcPendingTx) error
+}
+
+// run watches the pool until handling a transaction fails, reconnecting with
+// a growing delay.
+func (w *pendingWatcher) run() error {
+	delay := minReconnectDelay
+
+	for {
+		connected, err := w.follow()
+
+		if handleErr, ok := err.(*handlerError); ok {
+			return handleErr.err
+		}
+
+		if connected {
+			delay = minReconnectDelay
+		}
+
+		fmt.Fprintf(os.Stderr, "Watching pending transactions failed: %s, reconnecting in %s\n", err, delay)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (w *pendingWatcher) follow() (bool, error) {
+	rpcClient, err := rpc.Dial(viper.GetString("nodeuri"))
+	if err != nil {
+		return false, err
+	}
+	defer rpcClient.Close()
+
+	ctx := context.Background()
+	hashes := make(chan common.Hash, 1024)
+
+	sub, err := rpcClient.EthSubscribe(ctx, hashes, "newPendingTransactions")
+	if err == rpc.ErrNotificationsUnsupported {
+		return w.poll(rpcClient)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return true, err
+
+		case hash := <-hashes:
+			var tx *rpcPendingTx
+			if err := rpcClient.CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+				return true, err
+			}
+
+			// the transaction may be replaced or dropped already
+			if tx == nil {
+				continue
+			}
+
+			if err := w.handle(tx); err != nil {
+				return true, &handlerError{err}
+			}
+		}
+	}
+}
+
+// poll reports the transactions which appeared in the pending section of
+// txpool_content since the previous poll. Transactions already in the pool
+// when polling starts are reported too.
+func (w *pendingWatcher) poll(rpcClient *rpc.Client) (bool, error) {
+	ctx := context.Background()
+	seen := map[common.Hash]bool{}
+	connected := false
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		var content struct {
+			Pending map[string]map[string]*rpcPendingTx `json:"pending"`
+		}
+
+		if err := rpcClient.CallContext(ctx, &content, "txpool_content"); err != nil {
+			return connected, fmt.Errorf("txpool_content: %s", err)
+		}
+		connected = true
+
+		current := map[common.Hash]bool{}
+
+		for _, txs := range content.Pending {
+			for _, tx := range txs {
+				current[tx.Hash] = true
+				if seen[tx.Hash] {
+					continue
+				}
+
+				if err := w.handle(tx); err != nil {
+					return true, &handlerError{err}
+				}
+			}
+		}
+
+		// only remember what is still in the pool
+		seen = current
+
+		<-ticker.C
+	}
+}
+
+func watchPending(c *cli.Context) error {
+	registry, err := loadAbiRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	filter, err := buildPendingFilter(c, registry)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	w := newRecordWriter()
+
+	watcher := &pendingWatcher{
+		interval: c.Duration("interval"),
+		handle: func(tx *rpcPendingTx) error {
+			if !filter.matches(tx) {
+				return nil
+			}
+
+			result := newPendingTxResult(tx)
+			if len(tx.Input) > 0 && tx.To != nil {
+				result.Method = registry.decodeCall(tx.To, tx.Input)
+			}
+
+			return w.write(result)
+		},
+	}
+
+	err = watcher.run()
+	w.close()
+
+	return cli.NewExitError(err.Error(), 1)
+}
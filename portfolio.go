@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+
+	"github.com/jsgoyette/wanutil/contracts"
+
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/common"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+)
+
+// portfolioAsset is WAN (token is nil) or one of the configured tokens.
+type portfolioAsset struct {
+	symbol   string
+	decimals uint8
+	token    *contracts.Standard
+}
+
+type portfolioAddress struct {
+	address common.Address
+	label   string
+}
+
+type portfolioRow struct {
+	Address   string   `json:"address"`
+	Label     string   `json:"label"`
+	Asset     string   `json:"asset"`
+	Block     *big.Int `json:"block"`
+	Balance   string   `json:"balance"`
+	Formatted string   `json:"formatted"`
+}
+
+func (r *portfolioRow) printText() {
+	fmt.Printf("%s %s %s %s\n", r.Address, r.Label, r.Asset, r.Formatted)
+}
+
+func (r *portfolioRow) csvHeader() []string {
+	return []string{"address", "label", "asset", "block", "balance", "formatted"}
+}
+
+func (r *portfolioRow) csvRecord() []string {
+	return []string{r.Address, r.Label, r.Asset, r.Block.String(), r.Balance, r.Formatted}
+}
+
+func getPortfolio(c *cli.Context) error {
+	addresses, err := getPortfolioAddresses(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if len(addresses) == 0 {
+		return cli.NewExitError("No addresses provided", 1)
+	}
+
+	client := getWanchainConnection()
+
+	assets, err := getPortfolioAssets(client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	blockNumber := big.NewInt(c.Int64("block"))
+
+	if blockNumber.Cmp(ZERO) == 0 {
+		current, err := currentBlockNumber(client)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		blockNumber = current
+	}
+
+	balances, err := queryPortfolio(client, addresses, assets, blockNumber, c.Int("workers"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	totals := make([]*big.Int, len(assets))
+	for j := range assets {
+		totals[j] = new(big.Int)
+		for i := range addresses {
+			totals[j].Add(totals[j], balances[i][j])
+		}
+	}
+
+	if outputFormat == outputText {
+		printPortfolioTable(addresses, assets, balances, totals, blockNumber)
+		return nil
+	}
+
+	w := newRecordWriter()
+
+	for i, address := range addresses {
+		for j, asset := range assets {
+			row := &portfolioRow{
+				Address:   address.address.Hex(),
+				Label:     address.label,
+				Asset:     asset.symbol,
+				Block:     blockNumber,
+				Balance:   balances[i][j].String(),
+				Formatted: formatUnits(balances[i][j], asset.decimals),
+			}
+
+			if err := w.write(row); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+	}
+
+	for j, asset := range assets {
+		row := &portfolioRow{
+			Label:     "TOTAL",
+			Asset:     asset.symbol,
+			Block:     blockNumber,
+			Balance:   totals[j].String(),
+			Formatted: formatUnits(totals[j], asset.decimals),
+		}
+
+		if err := w.write(row); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// getPortfolioAddresses collects the addresses from the flags, the arguments
+// and the address file. Without any, the whole address book is used.
+func getPortfolioAddresses(c *cli.Context) ([]portfolioAddress, error) {
+	inputs := append(c.StringSlice("address"), c.Args()...)
+
+	if fileName := c.String("file"); fileName != "" {
+		lines, err := readAddressFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, lines...)
+	}
+
+	if len(inputs) == 0 && c.String("file") == "" {
+		for label := range viper.GetStringMapString("addressbook") {
+			inputs = append(inputs, label)
+		}
+		sort.Strings(inputs)
+	}
+
+	addresses := []portfolioAddress{}
+	seen := map[common.Address]bool{}
+
+	for _, input := range inputs {
+		label := ""
+
+		// file lines may hold a label and an address
+		if fields := strings.Fields(input); len(fields) == 2 {
+			label, input = fields[0], fields[1]
+		}
+
+		address, bookLabel, err := resolveAddress(input)
+		if err != nil {
+			return nil, err
+		}
+
+		if label == "" {
+			label = bookLabel
+		}
+
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		addresses = append(addresses, portfolioAddress{address: address, label: label})
+	}
+
+	return addresses, nil
+}
+
+// readAddressFile reads one address or label per line, optionally preceded
+// by a label. Empty lines and lines starting with # are skipped.
+func readAddressFile(fileName string) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// getPortfolioAssets returns WAN followed by the tokens in the config
+// contracts, ordered by symbol. Contracts which are not tokens are skipped.
+func getPortfolioAssets(client *wanclient.Client) ([]portfolioAsset, error) {
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	configured, err := getConfigContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []portfolioAsset{}
+
+	for name, contract := range configured {
+		if !contract.Token {
+			continue
+		}
+
+		info, err := resolveToken(client, registry, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+
+		instance, err := contracts.NewStandard(info.Address, client)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, portfolioAsset{
+			symbol:   info.Symbol,
+			decimals: info.Decimals,
+			token:    instance,
+		})
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].symbol < tokens[j].symbol
+	})
+
+	return append([]portfolioAsset{{symbol: "WAN", decimals: 18}}, tokens...), nil
+}
+
+// queryPortfolio fetches every asset balance for every address, spreading
+// the queries over a pool of workers.
+func queryPortfolio(client *wanclient.Client, addresses []portfolioAddress, assets []portfolioAsset, blockNumber *big.Int, workers int) ([][]*big.Int, error) {
+	type job struct {
+		address int
+		asset   int
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	balances := make([][]*big.Int, len(addresses))
+	for i := range balances {
+		balances[i] = make([]*big.Int, len(assets))
+	}
+
+	jobs := make(chan job)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				address := addresses[j.address].address
+				asset := assets[j.asset]
+
+				var balance *big.Int
+				var err error
+
+				if asset.token == nil {
+					balance, err = client.BalanceAt(context.Background(), address, blockNumber)
+				} else {
+					opts := &bind.CallOpts{BlockNumber: blockNumber, Context: context.Background()}
+					balance, err = asset.token.BalanceOf(opts, address)
+				}
+
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%s balance of %s: %s", asset.symbol, address.Hex(), err)
+					}
+					mu.Unlock()
+					continue
+				}
+
+				balances[j.address][j.asset] = balance
+			}
+		}()
+	}
+
+	for i := range addresses {
+		for j := range assets {
+			jobs <- job{address: i, asset: j}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return balances, firstErr
+}
+
+func printPortfolioTable(addresses []portfolioAddress, assets []portfolioAsset, balances [][]*big.Int, totals []*big.Int, blockNumber *big.Int) {
+	fmt.Printf("Portfolio at block %d\n\n", blockNumber)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	header := []string{"Address", "Label"}
+	for _, asset := range assets {
+		header = append(header, asset.symbol)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t")+"\t")
+
+	for i, address := range addresses {
+		row := []string{address.address.Hex(), address.label}
+		for j, asset := range assets {
+			row = append(row, formatUnits(balances[i][j], asset.decimals))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t")+"\t")
+	}
+
+	row := []string{"TOTAL", ""}
+	for j, asset := range assets {
+		row = append(row, formatUnits(totals[j], asset.decimals))
+	}
+	fmt.Fprintln(w, strings.Join(row, "\t")+"\t")
+
+	w.Flush()
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/urfave/cli"
 )
 
@@ -15,16 +17,101 @@ var (
 		Value: "",
 		Usage: "Address hash",
 	}
+	amountFlag = cli.StringFlag{
+		Name:  "amount",
+		Value: "",
+		Usage: "Token amount in whole tokens, e.g. 10.5",
+	}
+	batchFlag = cli.IntFlag{
+		Name:  "batch",
+		Value: 1,
+		Usage: "Number of blocks to request per JSON-RPC batch",
+	}
+	addressesFlag = cli.StringSliceFlag{
+		Name:  "address, a",
+		Usage: "Address hash or address book label (may be repeated)",
+	}
+	bumpFlag = cli.IntFlag{
+		Name:  "bump",
+		Value: 10,
+		Usage: "Minimum gas price increase in percent over the replaced transaction",
+	}
+	blocksFlag = cli.IntFlag{
+		Name:  "blocks",
+		Value: 20,
+		Usage: "Number of recent blocks to sample gas prices from, at most 500",
+	}
+	broadcastFlag = cli.BoolFlag{
+		Name:  "broadcast",
+		Usage: "Send the signed transaction to the node",
+	}
 	blockFlag = cli.IntFlag{
 		Name:  "block, b",
 		Value: 0,
 		Usage: "Block number",
 	}
+	chainIDFlag = cli.IntFlag{
+		Name:  "chain-id",
+		Value: 0,
+		Usage: "Chain ID to sign for (default: chainid from the config file or the node)",
+	}
+	checkpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Value: "",
+		Usage: "Checkpoint file (default: ~/.wanutil/checkpoints/scan-<direction>-<address>.json)",
+	}
+	confirmationsFlag = cli.IntFlag{
+		Name:  "confirmations",
+		Value: 1,
+		Usage: "Number of blocks, including the one it is mined in, to wait for on top of a transaction",
+	}
 	countFlag = cli.IntFlag{
 		Name:  "count, c",
 		Value: 20,
 		Usage: "Record count",
 	}
+	dataFlag = cli.StringFlag{
+		Name:  "data",
+		Value: "",
+		Usage: "Transaction input data as hex",
+	}
+	eventFlag = cli.StringFlag{
+		Name:  "event, e",
+		Value: "",
+		Usage: "Event topic, signature such as Transfer(address,address,uint256) or name in a known ABI",
+	}
+	execFlag = cli.StringSliceFlag{
+		Name:  "exec",
+		Usage: "Shell command run for each event with its JSON on stdin, repeat for several commands",
+	}
+	fileFlag = cli.StringFlag{
+		Name:  "file",
+		Value: "",
+		Usage: "File with one address or label per line",
+	}
+	followFlag = cli.BoolFlag{
+		Name:  "follow, f",
+		Usage: "Keep scanning new blocks as they arrive",
+	}
+	forceFlag = cli.BoolFlag{
+		Name:  "force",
+		Usage: "Start over, replacing an existing checkpoint",
+	}
+	fromFlag = cli.StringFlag{
+		Name:  "from",
+		Value: "",
+		Usage: "Keystore account label or address used to sign",
+	}
+	gasFlag = cli.IntFlag{
+		Name:  "gas",
+		Value: 0,
+		Usage: "Gas limit (estimated when not set)",
+	}
+	gasPriceFlag = cli.StringFlag{
+		Name:  "gas-price",
+		Value: "",
+		Usage: "Gas price in wei (suggested by the node when not set)",
+	}
 	hashFlag = cli.StringFlag{
 		Name:  "hash",
 		Value: "",
@@ -35,11 +122,155 @@ var (
 		Value: "",
 		Usage: "Hex string",
 	}
+	intervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Value: 10 * time.Second,
+		Usage: "Polling interval for new blocks",
+	}
+	keystoreFlag = cli.StringFlag{
+		Name:  "keystore",
+		Value: "",
+		Usage: "Encrypted keystore file used to sign",
+	}
+	privateKeyFileFlag = cli.StringFlag{
+		Name:  "private-key-file",
+		Value: "",
+		Usage: "File with a raw private key as hex, read from stdin when no other key is given",
+	}
+	labelFlag = cli.StringFlag{
+		Name:  "label, l",
+		Value: "",
+		Usage: "Label to reference the account by",
+	}
+	lastFlag = cli.IntFlag{
+		Name:  "last",
+		Value: 0,
+		Usage: "Only use the last N blocks",
+	}
+	methodFlag = cli.StringSliceFlag{
+		Name:  "method, m",
+		Usage: "Method selector, signature such as transfer(address,uint256) or name in a known ABI, repeat to match any of several",
+	}
+	nonceFlag = cli.IntFlag{
+		Name:  "nonce",
+		Value: 0,
+		Usage: "Transaction nonce (pending nonce of the sender when not set)",
+	}
+	networkFlag = cli.StringFlag{
+		Name:  "network, profile",
+		Value: "",
+		Usage: "Network profile from the config file",
+	}
+	offlineFlag = cli.BoolFlag{
+		Name:  "offline",
+		Usage: "Sign without connecting to a node (requires nonce, gas, gas price and chain ID)",
+	}
+	outputFlag = cli.StringFlag{
+		Name:  "output, o",
+		Value: outputText,
+		Usage: "Output format: text, json, ndjson or csv",
+	}
+	ownerFlag = cli.StringFlag{
+		Name:  "owner",
+		Value: "",
+		Usage: "Owner address or address book label",
+	}
+	pageFlag = cli.IntFlag{
+		Name:  "page",
+		Value: 5000,
+		Usage: "Number of blocks queried at once, halved when the node refuses a query",
+	}
+	passwordFileFlag = cli.StringFlag{
+		Name:  "password-file",
+		Value: "",
+		Usage: "File containing the keystore password",
+	}
+	recipientFlag = cli.StringSliceFlag{
+		Name:  "to",
+		Usage: "Recipient address or label, repeat to match any of several",
+	}
+	reorgDepthFlag = cli.IntFlag{
+		Name:  "reorg-depth",
+		Value: 12,
+		Usage: "Number of blocks rechecked for reorganizations after reconnecting",
+	}
+	resumeFlag = cli.BoolFlag{
+		Name:  "resume",
+		Usage: "Resume from the last checkpoint",
+	}
+	timeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Value: 0,
+		Usage: "Give up after this long, 0 waits forever",
+	}
+	toBlockFlag = cli.IntFlag{
+		Name:  "to-block",
+		Value: 0,
+		Usage: "End block number (inclusive)",
+	}
+	senderFlag = cli.StringSliceFlag{
+		Name:  "from",
+		Usage: "Sender address or label, repeat to match any of several",
+	}
+	sinkFileFlag = cli.StringSliceFlag{
+		Name:  "sink-file",
+		Usage: "File to append each event to as a line of JSON, repeat for several files",
+	}
+	spenderFlag = cli.StringFlag{
+		Name:  "spender",
+		Value: "",
+		Usage: "Spender address or address book label",
+	}
+	symbolFlag = cli.StringFlag{
+		Name:  "symbol",
+		Value: "",
+		Usage: "Token symbol to use instead of the one reported by the contract",
+	}
+	toFlag = cli.StringFlag{
+		Name:  "to",
+		Value: "",
+		Usage: "Recipient address or address book label",
+	}
+	topic1Flag = cli.StringSliceFlag{
+		Name:  "topic1",
+		Usage: "Value of the first indexed input, repeat to match any of several values",
+	}
+	topic2Flag = cli.StringSliceFlag{
+		Name:  "topic2",
+		Usage: "Value of the second indexed input, repeat to match any of several values",
+	}
+	topic3Flag = cli.StringSliceFlag{
+		Name:  "topic3",
+		Usage: "Value of the third indexed input, repeat to match any of several values",
+	}
 	tokenFlag = cli.StringFlag{
 		Name:  "token, t",
 		Value: "",
 		Usage: "Token name",
 	}
+	txTypeFlag = cli.IntFlag{
+		Name:  "txtype",
+		Value: NORMAL_TX,
+		Usage: "Wanchain transaction type",
+	}
+	valueFlag = cli.StringFlag{
+		Name:  "value",
+		Value: "",
+		Usage: "Amount of WAN to send, e.g. 1.5",
+	}
+	waitFlag = cli.BoolFlag{
+		Name:  "wait",
+		Usage: "Wait for the transaction receipt",
+	}
+	webhookFlag = cli.StringSliceFlag{
+		Name:  "webhook",
+		Usage: "URL to POST each event to as JSON, repeat for several URLs",
+	}
+	workersFlag = cli.IntFlag{
+		Name:  "workers, w",
+		Value: 4,
+		Usage: "Number of concurrent workers",
+	}
 
 	commands = []cli.Command{
 		{
@@ -47,7 +278,7 @@ var (
 			Aliases:     []string{"bal"},
 			Usage:       "Get address balance",
 			UsageText:   "wanutil balance [options]",
-			Description: "Get the balance or token balance for an address. To get the token balance, make sure to set the token address in your config file.",
+			Description: "Get the balance or token balance for an address. To get the token balance, set the token address in your config file, add the token to the registry with 'token add' or pass the token contract address.",
 			Action:      getBalance,
 			Flags:       []cli.Flag{addressFlag, blockFlag, tokenFlag},
 		},
@@ -60,32 +291,153 @@ var (
 			Action:      getBlock,
 			Flags:       []cli.Flag{blockFlag, hashFlag},
 		},
+		{
+			Name:        "portfolio",
+			Aliases:     []string{"pf"},
+			Usage:       "Get WAN and token balances for several addresses",
+			UsageText:   "wanutil portfolio [options] [address|label...]",
+			Description: "Get the WAN balance and the balance of every token in your config file for several addresses at a given block, with totals. Addresses can be given as arguments, with -a, in a file or as labels from the addressbook section of your config file. Without any addresses, the whole address book is used.",
+			Action:      getPortfolio,
+			Flags:       []cli.Flag{addressesFlag, blockFlag, fileFlag, workersFlag},
+		},
+		{
+			Name:        "token",
+			Aliases:     []string{"tok"},
+			Usage:       "Manage the token registry",
+			UsageText:   "wanutil token <command> [options]",
+			Description: "Look up token metadata (name, symbol, decimals and total supply) and manage the local token registry used to format token amounts.",
+			Subcommands: []cli.Command{
+				{
+					Name:        "info",
+					Usage:       "Get token metadata",
+					UsageText:   "wanutil token info [options]",
+					Description: "Fetch the metadata of a token by symbol or contract address and update the registry.",
+					Action:      getTokenInfo,
+					Flags:       []cli.Flag{addressFlag, tokenFlag},
+				},
+				{
+					Name:        "add",
+					Usage:       "Add a token to the registry",
+					UsageText:   "wanutil token add [options]",
+					Description: "Fetch the metadata of a token contract and add it to the registry, optionally under a different symbol.",
+					Action:      addToken,
+					Flags:       []cli.Flag{addressFlag, symbolFlag},
+				},
+				{
+					Name:        "transfer",
+					Usage:       "Transfer tokens",
+					UsageText:   "wanutil token transfer [options]",
+					Description: "Sign and send a token transfer. The amount is in whole tokens and scaled by the token decimals.",
+					Action:      transferToken,
+					Flags: []cli.Flag{
+						amountFlag, chainIDFlag, fromFlag, gasFlag, gasPriceFlag, keystoreFlag, nonceFlag,
+						passwordFileFlag, privateKeyFileFlag, toFlag, tokenFlag, waitFlag,
+					},
+				},
+				{
+					Name:        "approve",
+					Usage:       "Approve a token allowance",
+					UsageText:   "wanutil token approve [options]",
+					Description: "Sign and send an approve transaction allowing a spender to transfer tokens. The amount is in whole tokens and scaled by the token decimals.",
+					Action:      approveToken,
+					Flags: []cli.Flag{
+						amountFlag, chainIDFlag, fromFlag, gasFlag, gasPriceFlag, keystoreFlag, nonceFlag,
+						passwordFileFlag, privateKeyFileFlag, spenderFlag, tokenFlag, waitFlag,
+					},
+				},
+				{
+					Name:        "allowance",
+					Usage:       "Get a token allowance",
+					UsageText:   "wanutil token allowance [options]",
+					Description: "Get the amount of tokens a spender is allowed to transfer for an owner.",
+					Action:      getTokenAllowance,
+					Flags:       []cli.Flag{ownerFlag, spenderFlag, tokenFlag},
+				},
+				{
+					Name:        "list",
+					Usage:       "List the tokens in the registry",
+					UsageText:   "wanutil token list",
+					Description: "List the tokens in the registry of the selected network.",
+					Action:      listTokens,
+				},
+			},
+		},
+		{
+			Name:        "gas",
+			Usage:       "Estimate the gas and fee of a transaction",
+			UsageText:   "wanutil gas [options] [method [args...]]",
+			Description: "Estimate the gas of a contract method call, given like for the call command, or of a transaction given with --to, --value and --data, and print the fee in WAN at the gas price suggested by the node and at the minimum, median and other percentiles of the gas prices paid in the last --blocks blocks. Without a call the gas of a plain transfer is used; --gas skips the estimation.",
+			Action:      adviseGas,
+			Flags: []cli.Flag{
+				abiFileFlag, addressFlag, blocksFlag, dataFlag, fromFlag, gasFlag, toFlag, valueFlag,
+			},
+		},
+		{
+			Name:        "pending",
+			Usage:       "Watch pending transactions",
+			UsageText:   "wanutil pending [options]",
+			Description: "Print the transactions entering the transaction pool of the node, filtered by sender, recipient and method. Calls are decoded with the known ABIs. The node is asked for newPendingTransactions notifications; over HTTP its txpool_content is polled every --interval instead.",
+			Action:      watchPending,
+			Flags:       []cli.Flag{intervalFlag, methodFlag, recipientFlag, senderFlag},
+		},
 		{
 			Name:        "transaction",
 			Aliases:     []string{"tx"},
 			Usage:       "Get transaction by hash",
-			UsageText:   "wanutil transaction [options]",
-			Description: "Get transaction details and receipt. If it is to a recognized smart contract (in your config file) it will also try to parse the input.",
+			UsageText:   "wanutil transaction [command] [options]",
+			Description: "Get transaction details and receipt. The input and the events are decoded with the ABIs in the abidir and of the config contracts, or with the ABI given with --abi.",
 			Action:      getTransaction,
 			Flags:       []cli.Flag{abiFileFlag, hashFlag},
+			Subcommands: []cli.Command{
+				{
+					Name:        "wait",
+					Usage:       "Wait for a transaction to be mined",
+					UsageText:   "wanutil transaction wait [options] <hash>",
+					Description: "Wait until a transaction is mined and has --confirmations blocks, then print it with its decoded receipt. Exits with an error when the transaction fails, is replaced by another one with the same nonce, is dropped by the node or --timeout passes. New blocks are followed with a subscription when the node supports it and polled every --interval otherwise.",
+					Action:      waitTransaction,
+					Flags:       []cli.Flag{abiFileFlag, confirmationsFlag, hashFlag, intervalFlag, timeoutFlag},
+				},
+				{
+					Name:        "speedup",
+					Usage:       "Resend a pending transaction with a higher gas price",
+					UsageText:   "wanutil transaction speedup [options] <hash>",
+					Description: "Resend a pending transaction with the same nonce, recipient, value and input and a higher gas price, so it replaces the original. The gas price is the suggested one, but at least --bump percent over the original, unless --gas-price is given.",
+					Action:      speedUpTransaction,
+					Flags: []cli.Flag{
+						bumpFlag, chainIDFlag, fromFlag, gasFlag, gasPriceFlag, hashFlag, keystoreFlag,
+						passwordFileFlag, privateKeyFileFlag, waitFlag,
+					},
+				},
+				{
+					Name:        "cancel",
+					Usage:       "Cancel a pending transaction",
+					UsageText:   "wanutil transaction cancel [options] <hash>",
+					Description: "Replace a pending transaction with a zero-value transfer to the sender itself, with the same nonce and a higher gas price. The gas price is the suggested one, but at least --bump percent over the original, unless --gas-price is given.",
+					Action:      cancelTransaction,
+					Flags: []cli.Flag{
+						bumpFlag, chainIDFlag, fromFlag, gasFlag, gasPriceFlag, hashFlag, keystoreFlag,
+						passwordFileFlag, privateKeyFileFlag, waitFlag,
+					},
+				},
+			},
 		},
 		{
 			Name:        "transactionsToAddress",
 			Aliases:     []string{"scan-to"},
 			Usage:       "Scan blocks for transactions sent to a given address",
 			UsageText:   "wanutil transactionsToAddress [options]",
-			Description: "Scan blocks for transactions sent to a given address, using an optional block number range.",
+			Description: "Scan blocks for transactions sent to a given address, using an optional block number range. With --follow, keep scanning new blocks as they are mined.",
 			Action:      listTransactionsToAddress,
-			Flags:       []cli.Flag{addressFlag, blockFlag},
+			Flags:       []cli.Flag{addressFlag, batchFlag, blockFlag, checkpointFlag, followFlag, forceFlag, intervalFlag, lastFlag, resumeFlag, toBlockFlag, workersFlag},
 		},
 		{
 			Name:        "transactionsFromAddress",
 			Aliases:     []string{"scan-from"},
 			Usage:       "Scan blocks for transactions sent from a given address",
 			UsageText:   "wanutil transactionsFromAddress [options]",
-			Description: "Scan blocks for transactions sent from a given address, using an optional block number range.",
+			Description: "Scan blocks for transactions sent from a given address, using an optional block number range. With --follow, keep scanning new blocks as they are mined.",
 			Action:      listTransactionsFromAddress,
-			Flags:       []cli.Flag{addressFlag, blockFlag},
+			Flags:       []cli.Flag{addressFlag, batchFlag, blockFlag, checkpointFlag, followFlag, forceFlag, intervalFlag, lastFlag, resumeFlag, toBlockFlag, workersFlag},
 		},
 		{
 			Name:        "decodeTransaction",
@@ -101,9 +453,32 @@ var (
 			Aliases:     []string{"sig"},
 			Usage:       "Get ABI method/event signatures",
 			UsageText:   "wanutil signatures [options]",
-			Description: "Get the signature hashes for the methods and events for a given ABI.",
+			Description: "Get the signature hashes for the methods and events for a given ABI. The subcommands manage the signature database used to decode calls and events of contracts without a known ABI.",
 			Action:      listAbiSignatures,
 			Flags:       []cli.Flag{abiFileFlag},
+			Subcommands: []cli.Command{
+				{
+					Name:        "lookup",
+					Usage:       "Look up selectors or topics in the signature database",
+					UsageText:   "wanutil sig lookup <selector | topic>...",
+					Description: "Find the text signatures of 4-byte method selectors or 32-byte event topics.",
+					Action:      lookupSignature,
+				},
+				{
+					Name:        "import",
+					Usage:       "Import signatures into the signature database",
+					UsageText:   "wanutil sig import [file...]",
+					Description: "Import the signatures of ABI files (.abi or .json) or of text files with one signature per line, optionally preceded by its selector or topic. Without files, the ABIs in the abidir and of the config contracts are imported.",
+					Action:      importSignatures,
+				},
+				{
+					Name:        "add",
+					Usage:       "Add signatures to the signature database",
+					UsageText:   "wanutil sig add <signature>...",
+					Description: "Add text signatures such as 'transfer(address,uint256)', as both a method and an event.",
+					Action:      addSignatures,
+				},
+			},
 		},
 		{
 			Name:        "validate",
@@ -114,14 +489,98 @@ var (
 			Action:      validateAddress,
 			Flags:       []cli.Flag{addressFlag},
 		},
+		{
+			Name:        "send",
+			Aliases:     []string{"build-tx"},
+			Usage:       "Build and sign a transaction",
+			UsageText:   "wanutil send [options]",
+			Description: "Build a transaction, sign it with a keystore file or a raw key read from a file or stdin and print its RLP hex. With --broadcast it is sent to the node. The nonce, gas and gas price are looked up from the node unless given; with --offline nothing is looked up, so signing works on an air-gapped machine.",
+			Action:      sendTransaction,
+			Flags: []cli.Flag{
+				broadcastFlag, chainIDFlag, dataFlag, fromFlag, gasFlag, gasPriceFlag, keystoreFlag, nonceFlag,
+				offlineFlag, passwordFileFlag, privateKeyFileFlag, toFlag, txTypeFlag, valueFlag,
+			},
+		},
+		{
+			Name:        "account",
+			Aliases:     []string{"acc"},
+			Usage:       "Manage keystore accounts",
+			Description: "Manage the accounts in the ~/.wanutil/keystore directory. Labelled accounts can be used with --from to sign and in place of addresses in other commands.",
+			Subcommands: []cli.Command{
+				{
+					Name:        "new",
+					Usage:       "Create a new account",
+					UsageText:   "wanutil account new [options]",
+					Description: "Create a new account encrypted with a password.",
+					Action:      newAccount,
+					Flags:       []cli.Flag{labelFlag, passwordFileFlag},
+				},
+				{
+					Name:        "list",
+					Usage:       "List the accounts",
+					UsageText:   "wanutil account list",
+					Description: "List the accounts in the keystore directory with their labels.",
+					Action:      listAccounts,
+				},
+				{
+					Name:        "import",
+					Usage:       "Import a keystore file or private key",
+					UsageText:   "wanutil account import [options] [keystore file]",
+					Description: "Import a JSON keystore file, keeping its password, or a raw private key as hex, encrypted with a new password. Without a keystore file, the private key is read from --private-key-file or stdin, and prompted for without echo on a terminal.",
+					Action:      importAccount,
+					Flags:       []cli.Flag{labelFlag, passwordFileFlag, privateKeyFileFlag},
+				},
+				{
+					Name:        "inspect",
+					Usage:       "Show an account",
+					UsageText:   "wanutil account inspect [options] <label | address>",
+					Description: "Decrypt an account to check its password and show its address, file and public key.",
+					Action:      inspectAccount,
+					Flags:       []cli.Flag{passwordFileFlag},
+				},
+			},
+		},
+		{
+			Name:        "call",
+			Usage:       "Call a contract method",
+			UsageText:   "wanutil call [options] <method> [arguments...]",
+			Description: "Call a constant contract method with eth_call and decode the returned values with the ABI. The method is a name or a full signature for overloaded methods. Arrays and tuples are given as JSON, e.g. '[1,2]'.",
+			Action:      callContract,
+			Flags:       []cli.Flag{abiFileFlag, addressFlag, blockFlag},
+		},
+		{
+			Name:        "send-method",
+			Usage:       "Send a transaction calling a contract method",
+			UsageText:   "wanutil send-method [options] <method> [arguments...]",
+			Description: "Encode a call to a contract method which modifies the state (not view or pure), sign and send it, wait for the receipt and decode its events with the ABI. Gas, gas price and nonce are looked up from the node unless given. Use --value to send WAN to payable methods.",
+			Action:      sendMethod,
+			Flags: []cli.Flag{
+				abiFileFlag, addressFlag, chainIDFlag, fromFlag, gasFlag, gasPriceFlag, keystoreFlag,
+				nonceFlag, passwordFileFlag, privateKeyFileFlag, valueFlag,
+			},
+		},
+		{
+			Name:        "logs",
+			Usage:       "Query event logs",
+			UsageText:   "wanutil logs [options]",
+			Description: "Query the event logs of one or more addresses over a block range, by default the last 10000 blocks, optionally filtered by event and indexed input values. Large ranges are queried in pages. Logs of events with a known ABI or signature are decoded.",
+			Action:      getLogs,
+			Flags: []cli.Flag{
+				addressesFlag, blockFlag, eventFlag, lastFlag, pageFlag, toBlockFlag,
+				topic1Flag, topic2Flag, topic3Flag,
+			},
+		},
 		{
 			Name:        "subscribe",
 			Aliases:     []string{"sub"},
 			Usage:       "Subscribe to events for an address",
 			UsageText:   "wanutil subscribe [options]",
-			Description: "Subscribe to log events for a given address, starting from an optional block number",
+			Description: "Subscribe to log events for one or more addresses, starting from an optional block number. Takes the same filters as the logs command. The subscription reconnects when it fails and backfills the blocks it missed; logs dropped by a chain reorganization are reported again as removed. Over HTTP, which has no subscriptions, the node is polled every --interval. Besides being printed, events are sent to the sinks of the --webhook, --exec and --sink-file flags and of the sinks list in the config, which can filter events by name and address.",
 			Action:      subscribe,
-			Flags:       []cli.Flag{addressFlag, blockFlag},
+			Flags: []cli.Flag{
+				addressesFlag, blockFlag, eventFlag, execFlag, intervalFlag, reorgDepthFlag,
+				sinkFileFlag, topic1Flag, topic2Flag, topic3Flag, webhookFlag,
+			},
 		},
 	}
 )
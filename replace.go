@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// minPriceBump is the gas price increase in percent the transaction pool
+// requires by default to replace a transaction.
+const minPriceBump = 10
+
+// cancelGasLimit is the gas of the zero-value self-transfer sent to cancel a
+// transaction.
+var cancelGasLimit = big.NewInt(21000)
+
+// bumpGasPrice returns the price raised by percent, rounded up, which is how
+// the transaction pool compares a replacement with the original.
+func bumpGasPrice(price *big.Int, percent int64) *big.Int {
+	bumped := new(big.Int).Mul(price, big.NewInt(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// replaceTransaction sends a transaction with the nonce of a pending one and
+// a higher gas price, so the node replaces it. A speed up resends the same
+// transaction, a cancel a zero-value transfer to the sender itself.
+func replaceTransaction(c *cli.Context, cancel bool) error {
+	input := c.Args().First()
+	if input == "" {
+		input = c.String("hash")
+	}
+
+	if input == "" {
+		return cli.NewExitError("No tx hash provided", 1)
+	}
+
+	hash := common.HexToHash(input)
+
+	bump := c.Int64("bump")
+	if bump < minPriceBump {
+		return cli.NewExitError(fmt.Sprintf("The gas price bump must be at least %d percent, or the node refuses the replacement", minPriceBump), 1)
+	}
+
+	key, err := loadPrivateKey(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	client := getWanchainConnection()
+	ctx := context.Background()
+
+	original, isPending, err := client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if !isPending {
+		return cli.NewExitError(fmt.Sprintf("Transaction %s is already mined", hash.Hex()), 1)
+	}
+
+	chainID, err := getSigningChainID(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	sender, err := types.Sender(types.NewEIP155Signer(chainID), original)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if sender != from {
+		return cli.NewExitError(fmt.Sprintf("Transaction %s is sent by %s, not by %s", hash.Hex(), sender.Hex(), from.Hex()), 1)
+	}
+
+	// the pool may still list a transaction whose nonce is used by a
+	// transaction mined meanwhile
+	nonce, err := client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if nonce > original.Nonce() {
+		return cli.NewExitError(fmt.Sprintf("Nonce %d of transaction %s is already used", original.Nonce(), hash.Hex()), 1)
+	}
+
+	params, err := getTxParams(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	originalNonce := original.Nonce()
+	params.from = from
+	params.nonce = &originalNonce
+
+	if cancel {
+		params.to = &from
+		if params.gasLimit == nil {
+			params.gasLimit = cancelGasLimit
+		}
+	} else {
+		params.txType = original.Txtype()
+		params.to = original.To()
+		params.value = original.Value()
+		params.data = original.Data()
+		if params.gasLimit == nil {
+			params.gasLimit = original.Gas()
+		}
+	}
+
+	minGasPrice := bumpGasPrice(original.GasPrice(), bump)
+
+	if params.gasPrice == nil {
+		suggested, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		params.gasPrice = minGasPrice
+		if suggested.Cmp(minGasPrice) > 0 {
+			params.gasPrice = suggested
+		}
+	} else if params.gasPrice.Cmp(minGasPrice) < 0 {
+		return cli.NewExitError(fmt.Sprintf("Gas price must be at least %s to replace a transaction with gas price %s", minGasPrice, original.GasPrice()), 1)
+	}
+
+	tx, err := params.sign(key, chainID)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result, err := newSignedTxResult(tx, params.from)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Replacing %s (nonce %d, gas price %s) with gas price %s\n", hash.Hex(), originalNonce, original.GasPrice(), params.gasPrice)
+
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result.Sent = true
+
+	var waitErr error
+	if c.Bool("wait") {
+		_, waitErr = result.waitForReceipt(client, tx)
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if waitErr != nil {
+		return cli.NewExitError(waitErr.Error(), 1)
+	}
+
+	return nil
+}
+
+func speedUpTransaction(c *cli.Context) error {
+	return replaceTransaction(c, false)
+}
+
+func cancelTransaction(c *cli.Context) error {
+	return replaceTransaction(c, true)
+}
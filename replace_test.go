@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBumpGasPrice(t *testing.T) {
+	tests := []struct {
+		price   int64
+		percent int64
+		want    int64
+	}{
+		{180000000000, 10, 198000000000},
+		{180000000000, 25, 225000000000},
+		{100, 10, 110},
+		{101, 10, 112}, // 111.1 is rounded up
+		{1, 10, 2},
+		{0, 10, 0},
+		{199999999999, 10, 219999999999},
+	}
+
+	for _, test := range tests {
+		got := bumpGasPrice(big.NewInt(test.price), test.percent)
+		if got.Cmp(big.NewInt(test.want)) != 0 {
+			t.Errorf("%d bumped by %d%%: got %s, want %d", test.price, test.percent, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+type balanceResult struct {
+	Address   common.Address `json:"address"`
+	Token     string         `json:"token,omitempty"`
+	Block     *big.Int       `json:"block"`
+	Balance   string         `json:"balance"`
+	Formatted string         `json:"formatted"`
+}
+
+func (r *balanceResult) printText() {
+	if r.Token != "" {
+		fmt.Printf("%s balance at block %d: %s (%s)\n", r.Token, r.Block, r.Balance, r.Formatted)
+	} else {
+		fmt.Printf("Balance at block %d: %s (%s)\n", r.Block, r.Balance, r.Formatted)
+	}
+}
+
+func (r *balanceResult) csvHeader() []string {
+	return []string{"address", "token", "block", "balance", "formatted"}
+}
+
+func (r *balanceResult) csvRecord() []string {
+	return []string{r.Address.Hex(), r.Token, r.Block.String(), r.Balance, r.Formatted}
+}
+
+type blockResult struct {
+	Number       *big.Int       `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Time         *big.Int       `json:"timestamp"`
+	Miner        common.Address `json:"miner"`
+	GasLimit     string         `json:"gasLimit"`
+	GasUsed      string         `json:"gasUsed"`
+	Transactions []common.Hash  `json:"transactions"`
+
+	block *types.Block
+}
+
+func newBlockResult(block *types.Block) *blockResult {
+	hashes := []common.Hash{}
+	for _, tx := range block.Transactions() {
+		hashes = append(hashes, tx.Hash())
+	}
+
+	return &blockResult{
+		Number:       block.Number(),
+		Hash:         block.Hash(),
+		ParentHash:   block.ParentHash(),
+		Time:         block.Time(),
+		Miner:        block.Coinbase(),
+		GasLimit:     block.GasLimit().String(),
+		GasUsed:      block.GasUsed().String(),
+		Transactions: hashes,
+		block:        block,
+	}
+}
+
+func (r *blockResult) printText() {
+	fmt.Println(r.block)
+}
+
+func (r *blockResult) csvHeader() []string {
+	return []string{"number", "hash", "parentHash", "timestamp", "miner", "gasLimit", "gasUsed", "transactions"}
+}
+
+func (r *blockResult) csvRecord() []string {
+	return []string{
+		r.Number.String(),
+		r.Hash.Hex(),
+		r.ParentHash.Hex(),
+		r.Time.String(),
+		r.Miner.Hex(),
+		r.GasLimit,
+		r.GasUsed,
+		strconv.Itoa(len(r.Transactions)),
+	}
+}
+
+type transactionResult struct {
+	Hash     common.Hash        `json:"hash"`
+	To       *common.Address    `json:"to"`
+	From     string             `json:"from"`
+	TxType   uint64             `json:"txType"`
+	Value    string             `json:"value"`
+	Gas      string             `json:"gas"`
+	GasPrice string             `json:"gasPrice"`
+	Nonce    uint64             `json:"nonce"`
+	Size     common.StorageSize `json:"size"`
+	Data     hexutil.Bytes      `json:"data"`
+	V        *hexutil.Big       `json:"v"`
+	R        *hexutil.Big       `json:"r"`
+	S        *hexutil.Big       `json:"s"`
+	Pending  bool               `json:"pending"`
+}
+
+func newTransactionResult(tx *types.Transaction, from string, isPending bool) *transactionResult {
+	v, r, s := tx.RawSignatureValues()
+
+	return &transactionResult{
+		Hash:     tx.Hash(),
+		To:       tx.To(),
+		From:     from,
+		TxType:   tx.Txtype(),
+		Value:    tx.Value().String(),
+		Gas:      tx.Gas().String(),
+		GasPrice: tx.GasPrice().String(),
+		Nonce:    tx.Nonce(),
+		Size:     tx.Size(),
+		Data:     tx.Data(),
+		V:        (*hexutil.Big)(v),
+		R:        (*hexutil.Big)(r),
+		S:        (*hexutil.Big)(s),
+		Pending:  isPending,
+	}
+}
+
+func (r *transactionResult) printText() {
+	fmt.Printf("Hash: %s\n", r.Hash.Hex())
+	if r.To != nil {
+		fmt.Printf("To: %s\n", r.To.Hex())
+	}
+	fmt.Printf("From: %s\n", r.From)
+	fmt.Printf("TxType: 0x%x\n", r.TxType)
+	fmt.Printf("Value: %s\n", r.Value)
+	fmt.Printf("Gas: %s\n", r.Gas)
+	fmt.Printf("Gas Price: %s\n", r.GasPrice)
+	fmt.Printf("Nonce: %d\n", r.Nonce)
+	fmt.Printf("Size: %s\n", r.Size.String())
+
+	data := r.Data
+
+	if len(data) >= 4 {
+		fmt.Printf("Data: %x\n", data[:4])
+
+		b := 4
+		for len(data) >= b+32 {
+			fmt.Printf("      %x\n", data[b:b+32])
+			b = b + 32
+		}
+
+		if len(data) > b {
+			fmt.Printf("      %x\n", data[b:])
+		}
+	} else {
+		fmt.Printf("Data: %x\n", data)
+	}
+
+	fmt.Printf("V: 0x%x\n", r.V.ToInt())
+	fmt.Printf("R: 0x%x\n", r.R.ToInt())
+	fmt.Printf("S: 0x%x\n\n", r.S.ToInt())
+	fmt.Printf("Pending: %v\n\n", r.Pending)
+}
+
+func (r *transactionResult) csvHeader() []string {
+	return []string{"hash", "from", "to", "txType", "value", "gas", "gasPrice", "nonce", "pending"}
+}
+
+func (r *transactionResult) csvRecord() []string {
+	to := ""
+	if r.To != nil {
+		to = r.To.Hex()
+	}
+
+	return []string{
+		r.Hash.Hex(),
+		r.From,
+		to,
+		strconv.FormatUint(r.TxType, 10),
+		r.Value,
+		r.Gas,
+		r.GasPrice,
+		strconv.FormatUint(r.Nonce, 10),
+		strconv.FormatBool(r.Pending),
+	}
+}
+
+type receiptResult struct {
+	Status            uint           `json:"status"`
+	CumulativeGasUsed string         `json:"cumulativeGasUsed"`
+	GasUsed           string         `json:"gasUsed"`
+	ContractAddress   common.Address `json:"contractAddress"`
+	Bloom             hexutil.Bytes  `json:"logsBloom"`
+	Logs              []*logResult   `json:"logs"`
+}
+
+func newReceiptResult(receipt *types.Receipt) *receiptResult {
+	logs := []*logResult{}
+	for _, log := range receipt.Logs {
+		logs = append(logs, newLogResult(log))
+	}
+
+	return &receiptResult{
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed.String(),
+		GasUsed:           receipt.GasUsed.String(),
+		ContractAddress:   receipt.ContractAddress,
+		Bloom:             receipt.Bloom[:],
+		Logs:              logs,
+	}
+}
+
+func (r *receiptResult) printText() {
+	fmt.Printf("Status: %d\n", r.Status)
+	fmt.Printf("Cumulative Gas Used: %s\n", r.CumulativeGasUsed)
+	fmt.Printf("Contract Address: %x\n", r.ContractAddress)
+	fmt.Printf("Bloom: %x\n", []byte(r.Bloom))
+	fmt.Printf("Logs:\n")
+
+	for _, log := range r.Logs {
+		log.printText()
+	}
+}
+
+type logResult struct {
+	Address     common.Address `json:"address"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber uint64         `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     uint           `json:"transactionIndex"`
+	Index       uint           `json:"logIndex"`
+	Removed     bool           `json:"removed"`
+	Data        hexutil.Bytes  `json:"data"`
+	Topics      []common.Hash  `json:"topics"`
+	Event       *decodedEvent  `json:"event,omitempty"`
+}
+
+func newLogResult(log *types.Log) *logResult {
+	return &logResult{
+		Address:     log.Address,
+		BlockHash:   log.BlockHash,
+		BlockNumber: log.BlockNumber,
+		TxHash:      log.TxHash,
+		TxIndex:     log.TxIndex,
+		Index:       log.Index,
+		Removed:     log.Removed,
+		Data:        log.Data,
+		Topics:      log.Topics,
+	}
+}
+
+func (r *logResult) printText() {
+	if r.Removed {
+		fmt.Println("Removed by chain reorganization:")
+	}
+	fmt.Printf("\tAddress: %s\n", r.Address.Hex())
+	fmt.Printf("\tBlock Hash: %s\n", r.BlockHash.Hex())
+	fmt.Printf("\tBlock Number: %d\n", r.BlockNumber)
+	fmt.Printf("\tRemoved: %v\n", r.Removed)
+	fmt.Printf("\tData: %x\n", []byte(r.Data))
+	fmt.Printf("\tTopics:\n")
+	for _, topic := range r.Topics {
+		fmt.Printf("\t\t%x\n", topic)
+	}
+	fmt.Println()
+
+	if r.Event != nil {
+		r.Event.printText()
+	}
+}
+
+func (r *logResult) csvHeader() []string {
+	return []string{"address", "blockHash", "blockNumber", "transactionHash", "logIndex", "removed", "data", "topics", "event"}
+}
+
+func (r *logResult) csvRecord() []string {
+	topics := make([]string, len(r.Topics))
+	for i, topic := range r.Topics {
+		topics[i] = topic.Hex()
+	}
+
+	event := ""
+	if r.Event != nil {
+		event = r.Event.Signature
+	}
+
+	return []string{
+		r.Address.Hex(),
+		r.BlockHash.Hex(),
+		strconv.FormatUint(r.BlockNumber, 10),
+		r.TxHash.Hex(),
+		strconv.FormatUint(uint64(r.Index), 10),
+		strconv.FormatBool(r.Removed),
+		hexutil.Encode(r.Data),
+		strings.Join(topics, " "),
+		event,
+	}
+}
+
+type decodedValue struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func newDecodedValues(inputs []AbiArgument, values []interface{}) []decodedValue {
+	decoded := make([]decodedValue, len(inputs))
+	for i, input := range inputs {
+		decoded[i] = decodedValue{
+			Name:  input.Name,
+			Type:  input.Type.String(),
+			Value: jsonAbiValue(input.Type, values[i]),
+		}
+	}
+	return decoded
+}
+
+// decodedCall is the method call decoded from a transaction input.
+type decodedCall struct {
+	Name      string         `json:"name"`
+	Selector  string         `json:"selector"`
+	Signature string         `json:"signature"`
+	Inputs    []decodedValue `json:"inputs"`
+	Error     string         `json:"error,omitempty"`
+
+	method *AbiMethod
+	values []interface{}
+}
+
+func newDecodedCall(method *AbiMethod, data []byte) *decodedCall {
+	call := &decodedCall{
+		Name:      method.Name,
+		Selector:  method.SignatureHash[:10],
+		Signature: method.Signature,
+		method:    method,
+	}
+
+	values, err := decodeAbiValues(getArgumentTypes(method.Inputs), data)
+	if err != nil {
+		call.Error = err.Error()
+		return call
+	}
+
+	call.values = values
+	call.Inputs = newDecodedValues(method.Inputs, values)
+
+	return call
+}
+
+func (r *decodedCall) printText() {
+	if r.method == nil {
+		fmt.Printf("Method: %s\tError: %s\n\n", r.Selector, r.Error)
+		return
+	}
+
+	fmt.Println("Method:", r.Name)
+	fmt.Println("Signature:", r.Signature)
+	fmt.Println("Inputs:", getInputNamesString(r.method.Inputs))
+	fmt.Printf("Values:")
+
+	if r.Error != "" {
+		fmt.Printf("\tError: %s\n\n", r.Error)
+		return
+	}
+
+	printValues(r.method.Inputs, r.values)
+	fmt.Println()
+}
+
+// decodedEvent is an event decoded from a log.
+type decodedEvent struct {
+	Name      string         `json:"name"`
+	Address   common.Address `json:"address"`
+	Signature string         `json:"signature"`
+	Inputs    []decodedValue `json:"inputs"`
+	Error     string         `json:"error,omitempty"`
+
+	method *AbiMethod
+	values []interface{}
+}
+
+func newDecodedEvent(method *AbiMethod, log *types.Log) *decodedEvent {
+	event := &decodedEvent{
+		Name:      method.Name,
+		Address:   log.Address,
+		Signature: method.Signature,
+		method:    method,
+	}
+
+	values, err := decodeEventValues(method, log)
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+
+	event.values = values
+	event.Inputs = newDecodedValues(method.Inputs, values)
+
+	return event
+}
+
+func (r *decodedEvent) printText() {
+	fmt.Println("Event:", r.Name)
+	fmt.Println("Address:", r.Address.Hex())
+	fmt.Println("Signature:", r.Signature)
+	fmt.Println("Inputs:", getInputNamesString(r.method.Inputs))
+	fmt.Printf("Values:")
+
+	if r.Error != "" {
+		fmt.Printf("\tError: %s\n\n", r.Error)
+		return
+	}
+
+	printValues(r.method.Inputs, r.values)
+	fmt.Println()
+}
+
+func printValues(inputs []AbiArgument, values []interface{}) {
+	for i, input := range inputs {
+		name := input.Name
+		if name == "" {
+			// inputs of signatures from the signature database have no names
+			name = fmt.Sprintf("[%d]", i)
+		}
+		fmt.Printf("\t%v = %v\n", name, formatAbiValue(input.Type, values[i]))
+	}
+}
+
+// transactionDetails is the result of the transaction command: the
+// transaction, the receipt once it is mined and whatever could be decoded.
+type transactionDetails struct {
+	Transaction *transactionResult `json:"transaction"`
+	Method      *decodedCall       `json:"method,omitempty"`
+	Events      []*decodedEvent    `json:"events,omitempty"`
+	Receipt     *receiptResult     `json:"receipt,omitempty"`
+}
+
+func (r *transactionDetails) printText() {
+	r.Transaction.printText()
+
+	if r.Method != nil {
+		r.Method.printText()
+	}
+
+	for _, event := range r.Events {
+		event.printText()
+	}
+
+	if r.Receipt != nil {
+		r.Receipt.printText()
+	}
+}
+
+func (r *transactionDetails) csvHeader() []string {
+	return append(r.Transaction.csvHeader(), "status", "gasUsed", "method")
+}
+
+func (r *transactionDetails) csvRecord() []string {
+	status, gasUsed, method := "", "", ""
+
+	if r.Receipt != nil {
+		status = strconv.FormatUint(uint64(r.Receipt.Status), 10)
+		gasUsed = r.Receipt.GasUsed
+	}
+
+	if r.Method != nil {
+		method = r.Method.Name
+	}
+
+	return append(r.Transaction.csvRecord(), status, gasUsed, method)
+}
+
+func (m scanMatch) printText() {
+	fmt.Printf("%7d | %s\n", m.Block, m.Hash.Hex())
+}
+
+func (m scanMatch) csvHeader() []string {
+	return []string{"block", "hash"}
+}
+
+func (m scanMatch) csvRecord() []string {
+	return []string{strconv.FormatUint(m.Block, 10), m.Hash.Hex()}
+}
+
+type signatureResult struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+	Inputs    string `json:"inputs"`
+}
+
+func (r *signatureResult) printText() {
+	fmt.Printf("%s %s %s\n", r.Hash, r.Signature, r.Inputs)
+}
+
+func (r *signatureResult) csvHeader() []string {
+	return []string{"hash", "signature", "inputs"}
+}
+
+func (r *signatureResult) csvRecord() []string {
+	return []string{r.Hash, r.Signature, r.Inputs}
+}
+
+type addressResult struct {
+	Input   string          `json:"input"`
+	Valid   bool            `json:"valid"`
+	Address *common.Address `json:"address,omitempty"`
+}
+
+func (r *addressResult) printText() {
+	if !r.Valid {
+		fmt.Println("Address is INVALID")
+	} else {
+		fmt.Printf("Valid address: %s\n", r.Address.Hex())
+	}
+}
+
+func (r *addressResult) csvHeader() []string {
+	return []string{"input", "valid", "address"}
+}
+
+func (r *addressResult) csvRecord() []string {
+	address := ""
+	if r.Address != nil {
+		address = r.Address.Hex()
+	}
+
+	return []string{r.Input, strconv.FormatBool(r.Valid), address}
+}
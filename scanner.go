@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// scanBlock holds the parts of an eth_getBlockByNumber response needed to
+// match transactions against an address. Using the raw RPC response saves
+// recovering the sender from each signature.
+type scanBlock struct {
+	Number       *hexutil.Big
+	Transactions []scanTransaction
+}
+
+type scanTransaction struct {
+	Hash common.Hash
+	From common.Address
+	To   *common.Address
+}
+
+type scanReceipt struct {
+	ContractAddress *common.Address
+}
+
+type scanMatch struct {
+	Block uint64      `json:"block"`
+	Hash  common.Hash `json:"hash"`
+}
+
+// scanChunk is a range of blocks processed by a single worker.
+type scanChunk struct {
+	index   int64
+	from    int64
+	to      int64
+	matches []scanMatch
+	err     error
+}
+
+// blockScanner looks for transactions sent to or from an address, spreading
+// the block fetches over a pool of workers.
+type blockScanner struct {
+	client    *rpc.Client
+	address   common.Address
+	direction string
+	workers   int
+	batchSize int
+}
+
+// scan processes the blocks from..to (inclusive) and hands every chunk to
+// handle in block order, whether or not it has matches.
+func (s *blockScanner) scan(ctx context.Context, from, to int64, handle func(*scanChunk) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := s.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	chunkSize := int64(s.batchSize)
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	jobs := make(chan *scanChunk)
+	results := make(chan *scanChunk)
+
+	// window limits how far workers can run ahead of the chunk that is
+	// next in line to be handled
+	window := make(chan struct{}, workers*4)
+
+	go func() {
+		defer close(jobs)
+
+		for index, start := int64(0), from; start <= to; index, start = index+1, start+chunkSize {
+			end := start + chunkSize - 1
+			if end > to {
+				end = to
+			}
+
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- &scanChunk{index: index, from: start, to: end}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for chunk := range jobs {
+				chunk.matches, chunk.err = s.fetchMatches(ctx, chunk.from, chunk.to)
+
+				select {
+				case results <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := map[int64]*scanChunk{}
+	next := int64(0)
+
+	for chunk := range results {
+		pending[chunk.index] = chunk
+
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+
+			delete(pending, next)
+
+			if ready.err != nil {
+				return ready.err
+			}
+
+			if err := handle(ready); err != nil {
+				return err
+			}
+
+			<-window
+			next++
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (s *blockScanner) fetchMatches(ctx context.Context, from, to int64) ([]scanMatch, error) {
+	blocks := make([]*scanBlock, to-from+1)
+	elems := make([]rpc.BatchElem, len(blocks))
+
+	for i := range elems {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(big.NewInt(from + int64(i))), true},
+			Result: &blocks[i],
+		}
+	}
+
+	if err := batchCallRPC(ctx, s.client, elems, s.batchSize); err != nil {
+		return nil, err
+	}
+
+	matches := []scanMatch{}
+	creations := []common.Hash{}
+	isCreation := map[common.Hash]bool{}
+
+	for i, block := range blocks {
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", from+int64(i))
+		}
+
+		number := block.Number.ToInt().Uint64()
+
+		for _, tx := range block.Transactions {
+			match := scanMatch{Block: number, Hash: tx.Hash}
+
+			if s.direction == "to" && tx.To == nil {
+				// check receipt contract address
+				matches = append(matches, match)
+				creations = append(creations, tx.Hash)
+				isCreation[tx.Hash] = true
+			} else if s.direction == "to" && *tx.To == s.address {
+				matches = append(matches, match)
+			} else if s.direction == "from" && tx.From == s.address {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	if len(creations) == 0 {
+		return matches, nil
+	}
+
+	receipts := make([]*scanReceipt, len(creations))
+	elems = make([]rpc.BatchElem, len(creations))
+
+	for i, hash := range creations {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{hash},
+			Result: &receipts[i],
+		}
+	}
+
+	if err := batchCallRPC(ctx, s.client, elems, s.batchSize); err != nil {
+		return nil, err
+	}
+
+	created := map[common.Hash]bool{}
+	for i, receipt := range receipts {
+		if receipt != nil && receipt.ContractAddress != nil && *receipt.ContractAddress == s.address {
+			created[creations[i]] = true
+		}
+	}
+
+	filtered := matches[:0]
+	for _, match := range matches {
+		if !isCreation[match.Hash] || created[match.Hash] {
+			filtered = append(filtered, match)
+		}
+	}
+
+	return filtered, nil
+}
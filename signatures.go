@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+// signatureDB maps 4-byte method selectors and event topics to text
+// signatures. It is kept in ~/.wanutil/signatures.json and used to decode
+// calls and events of contracts whose ABI is unknown.
+type signatureDB struct {
+	Methods map[string][]string `json:"methods"`
+	Events  map[string][]string `json:"events"`
+
+	path    string
+	changed bool
+}
+
+func loadSignatureDB() (*signatureDB, error) {
+	path, err := getWanutilPath("signatures.json")
+	if err != nil {
+		return nil, err
+	}
+
+	db := &signatureDB{
+		Methods: map[string][]string{},
+		Events:  map[string][]string{},
+		path:    path,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if db.Methods == nil {
+		db.Methods = map[string][]string{}
+	}
+	if db.Events == nil {
+		db.Events = map[string][]string{}
+	}
+
+	return db, nil
+}
+
+func (db *signatureDB) save() error {
+	if !db.changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(db.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(db.path, data, 0600)
+}
+
+func addSignatureTo(index map[string][]string, key string, sig string) bool {
+	for _, existing := range index[key] {
+		if existing == sig {
+			return false
+		}
+	}
+
+	index[key] = append(index[key], sig)
+	return true
+}
+
+// addSignature adds a text signature. A method and an event with the same
+// signature only differ in the length of their hash, so unless hash is given
+// the signature is added as both.
+func (db *signatureDB) addSignature(sig string, hash string) error {
+	method, err := parseSignature(sig)
+	if err != nil {
+		return err
+	}
+
+	hash = strings.ToLower(hash)
+	if hash != "" && !strings.HasPrefix(method.SignatureHash, hash) {
+		return fmt.Errorf("Hash %s does not match %s", hash, method.Signature)
+	}
+
+	if hash == "" || len(hash) == 10 {
+		db.changed = addSignatureTo(db.Methods, method.SignatureHash[:10], method.Signature) || db.changed
+	}
+	if hash == "" || len(hash) == 66 {
+		db.changed = addSignatureTo(db.Events, method.SignatureHash, method.Signature) || db.changed
+	}
+
+	return nil
+}
+
+// addAbi adds the method and event signatures of an ABI.
+func (db *signatureDB) addAbi(fields []AbiField) {
+	for _, field := range fields {
+		if field.Name == "" {
+			continue
+		}
+
+		sig, sigHash := buildSignature(&field)
+
+		if field.Type == "event" {
+			db.changed = addSignatureTo(db.Events, sigHash, sig) || db.changed
+		} else if field.Type == "" || field.Type == "function" {
+			db.changed = addSignatureTo(db.Methods, sigHash[:10], sig) || db.changed
+		}
+	}
+}
+
+// importFile adds the signatures of an ABI file (.abi or .json) or of a text
+// file with a signature per line, optionally preceded by its selector or
+// topic.
+func (db *signatureDB) importFile(fileName string) error {
+	if ext := filepath.Ext(fileName); ext == ".abi" || ext == ".json" {
+		fields, err := parseAbi(fileName)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fileName, err)
+		}
+
+		db.addAbi(fields)
+		return nil
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash := ""
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == '\t' || r == ' '
+		})
+
+		if len(fields) > 1 && strings.HasPrefix(fields[0], "0x") {
+			hash = fields[0]
+			line = strings.TrimSpace(line[len(hash)+1:])
+		}
+
+		if err := db.addSignature(line, hash); err != nil {
+			return fmt.Errorf("%s:%d: %s", fileName, lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// lookup returns the signatures of a selector or topic. A 32 byte hash may
+// be an event topic or the full hash of a method signature.
+func (db *signatureDB) lookup(hash string) ([]*signatureResult, error) {
+	hash = strings.ToLower(hash)
+	if !strings.HasPrefix(hash, "0x") {
+		hash = "0x" + hash
+	}
+
+	if _, err := hex.DecodeString(hash[2:]); err != nil || (len(hash) != 10 && len(hash) != 66) {
+		return nil, fmt.Errorf("Not a 4-byte selector or 32-byte topic: %s", hash)
+	}
+
+	results := []*signatureResult{}
+
+	for _, sig := range db.Methods[hash[:10]] {
+		results = append(results, &signatureResult{Hash: hash[:10], Signature: sig})
+	}
+
+	if len(hash) == 66 {
+		for _, sig := range db.Events[hash] {
+			results = append(results, &signatureResult{Hash: hash, Signature: sig})
+		}
+	}
+
+	return results, nil
+}
+
+// method returns the first signature of the selector that the call data
+// decodes with.
+func (db *signatureDB) method(data []byte) *AbiMethod {
+	if len(data) < 4 {
+		return nil
+	}
+
+	for _, sig := range db.Methods[hexutil.Encode(data[:4])] {
+		method, err := parseSignature(sig)
+		if err != nil {
+			continue
+		}
+
+		method.Type = "function"
+
+		if _, err := decodeAbiValues(getArgumentTypes(method.Inputs), data[4:]); err == nil {
+			return method
+		}
+	}
+
+	return nil
+}
+
+// event returns the first signature of the topic that the log decodes with.
+// Signatures do not tell which inputs are indexed, so the leading inputs are
+// assumed to be, one per topic.
+func (db *signatureDB) event(log *types.Log) *AbiMethod {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+
+	indexed := len(log.Topics) - 1
+
+	for _, sig := range db.Events[log.Topics[0].Hex()] {
+		event, err := parseSignature(sig)
+		if err != nil || indexed > len(event.Inputs) {
+			continue
+		}
+
+		event.Type = "event"
+		for i := 0; i < indexed; i++ {
+			event.Inputs[i].Indexed = true
+		}
+
+		if _, err := decodeEventValues(event, log); err == nil {
+			return event
+		}
+	}
+
+	return nil
+}
+
+// parseSignature parses a text signature such as
+// transfer(address,uint256) into a method with unnamed inputs.
+func parseSignature(sig string) (*AbiMethod, error) {
+	sig = strings.Replace(sig, " ", "", -1)
+
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return nil, fmt.Errorf("Invalid signature: %s", sig)
+	}
+
+	inputs, err := parseSignatureTypes(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid signature %s: %s", sig, err)
+	}
+
+	method := &AbiMethod{AbiField: AbiField{Name: sig[:open], Inputs: inputs}}
+	method.Signature, method.SignatureHash = buildSignature(&method.AbiField)
+
+	return method, nil
+}
+
+// parseSignatureTypes parses a comma separated list of types, in which
+// tuples are written as (type1,type2).
+func parseSignatureTypes(list string) ([]AbiArgument, error) {
+	args := []AbiArgument{}
+	if list == "" {
+		return args, nil
+	}
+
+	depth := 0
+	start := 0
+
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				if depth < 0 {
+					return nil, errors.New("unbalanced parentheses")
+				}
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		if depth != 0 {
+			return nil, errors.New("unbalanced parentheses")
+		}
+
+		typ, err := parseSignatureType(list[start:i])
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, AbiArgument{Type: typ})
+		start = i + 1
+	}
+
+	return args, nil
+}
+
+func parseSignatureType(typ string) (*AbiType, error) {
+	if !strings.HasPrefix(typ, "(") {
+		return newAbiType(typ, nil)
+	}
+
+	end := strings.LastIndex(typ, ")")
+
+	components, err := parseSignatureTypes(typ[1:end])
+	if err != nil {
+		return nil, err
+	}
+
+	return newAbiType("tuple"+typ[end+1:], components)
+}
+
+func lookupSignature(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.NewExitError("No selector or topic provided", 1)
+	}
+
+	db, err := loadSignatureDB()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	w := newRecordWriter()
+	found := false
+
+	for _, hash := range c.Args() {
+		results, err := db.lookup(hash)
+		if err != nil {
+			w.close()
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		for _, result := range results {
+			found = true
+			if err := w.write(result); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if !found {
+		return cli.NewExitError("No signature found", 1)
+	}
+
+	return nil
+}
+
+// importSignatures imports signature files, or without any the ABIs of the
+// ABI registry.
+func importSignatures(c *cli.Context) error {
+	db, err := loadSignatureDB()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	methods, events := len(db.Methods), len(db.Events)
+
+	if c.NArg() == 0 {
+		registry, err := loadAbiRegistry()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		db.addAbi(registry.all.fields)
+	}
+
+	for _, fileName := range c.Args() {
+		if err := db.importFile(fileName); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := db.save(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d selectors and %d topics\n", len(db.Methods)-methods, len(db.Events)-events)
+
+	return nil
+}
+
+func addSignatures(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.NewExitError("No signature provided", 1)
+	}
+
+	db, err := loadSignatureDB()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	for _, sig := range c.Args() {
+		if err := db.addSignature(sig, ""); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := db.save(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
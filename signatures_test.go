@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		sig      string
+		want     string // empty when parsing must fail
+		selector string
+	}{
+		{"transfer(address,uint256)", "transfer(address,uint256)", "0xa9059cbb"},
+		{"transfer(address, uint256)", "transfer(address,uint256)", "0xa9059cbb"},
+		{" transfer (address,uint256) ", "transfer(address,uint256)", "0xa9059cbb"},
+		{"totalSupply()", "totalSupply()", "0x18160ddd"},
+		{"f(uint256,uint32[],bytes10,bytes)", "f(uint256,uint32[],bytes10,bytes)", "0x8be65246"},
+		{"f((uint256,address),bool)", "f((uint256,address),bool)", ""},
+		{"f((uint256,(bytes,string))[2],uint8)", "f((uint256,(bytes,string))[2],uint8)", ""},
+		{"transfer", "", ""},
+		{"(address)", "", ""},
+		{"transfer(address", "", ""},
+		{"transfer(address))", "", ""},
+		{"f((uint256,address)", "", ""},
+		{"f(uint256))(", "", ""},
+		{"transfer(adress,uint256)", "", ""},
+		{"f(uint7)", "", ""},
+	}
+
+	for _, test := range tests {
+		method, err := parseSignature(test.sig)
+
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", test.sig, method.Signature)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %s", test.sig, err)
+			continue
+		}
+
+		if method.Signature != test.want {
+			t.Errorf("%q: got %s, want %s", test.sig, method.Signature, test.want)
+		}
+
+		if test.selector != "" && method.SignatureHash[:10] != test.selector {
+			t.Errorf("%q: got selector %s, want %s", test.sig, method.SignatureHash[:10], test.selector)
+		}
+
+	}
+}
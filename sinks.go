@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+const (
+	sinkTimeout        = 10 * time.Second
+	defaultSinkRetries = 3
+
+	// sinkQueueSize is the number of events a sink can fall behind before
+	// the subscription waits for it
+	sinkQueueSize = 1024
+)
+
+// eventSink receives the JSON of each event, as written by -o ndjson.
+type eventSink interface {
+	send(payload []byte) error
+}
+
+// webhookSink posts events to a URL, retrying failed requests with a growing
+// delay. Only network errors, server errors and 429 Too Many Requests are
+// retried; other responses mean the request itself is refused.
+type webhookSink struct {
+	url     string
+	retries int
+	client  *http.Client
+}
+
+func (s *webhookSink) send(payload []byte) error {
+	delay := time.Second
+	var err error
+
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		var resp *http.Response
+		resp, err = s.client.Post(s.url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			continue
+		}
+
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+
+		err = fmt.Errorf("%s returned %s", s.url, resp.Status)
+
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+
+	return err
+}
+
+// execSink runs a shell command with the event on its stdin.
+type execSink struct {
+	command string
+}
+
+func (s *execSink) send(payload []byte) error {
+	cmd := exec.Command("sh", "-c", s.command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	done := make(chan error, 1)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(sinkTimeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("%s timed out", s.command)
+	}
+}
+
+// fileSink appends events to a file, one JSON object per line.
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) send(payload []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(payload, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// sinkConfig is an entry of the sinks list in the config file.
+type sinkConfig struct {
+	Type      string
+	URL       string
+	Command   string
+	Path      string
+	Retries   *int
+	Events    []string
+	Addresses []string
+}
+
+// filteredSink is a sink with the events it receives. Without filters it
+// receives every event. Events are delivered in order by a goroutine of the
+// sink, so a slow or retrying sink does not hold up the subscription.
+type filteredSink struct {
+	name      string
+	sink      eventSink
+	events    []string
+	addresses []common.Address
+
+	queue chan []byte
+	done  chan struct{}
+}
+
+// start delivers the queued events until the queue is closed.
+func (s *filteredSink) start() {
+	s.queue = make(chan []byte, sinkQueueSize)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		for payload := range s.queue {
+			if err := s.sink.send(payload); err != nil {
+				fmt.Fprintf(os.Stderr, "Sink %s failed: %s\n", s.name, err)
+			}
+		}
+	}()
+}
+
+// enqueue queues an event for delivery. When the sink is too far behind it
+// waits for it, which holds up the subscription rather than losing events.
+func (s *filteredSink) enqueue(payload []byte) {
+	select {
+	case s.queue <- payload:
+	default:
+		fmt.Fprintf(os.Stderr, "Sink %s is %d events behind, waiting for it\n", s.name, sinkQueueSize)
+		s.queue <- payload
+	}
+}
+
+// stop waits until the queued events are delivered.
+func (s *filteredSink) stop() {
+	close(s.queue)
+	<-s.done
+}
+
+func (s *filteredSink) matches(result *logResult) bool {
+	if len(s.addresses) > 0 {
+		found := false
+		for _, address := range s.addresses {
+			if address == result.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(s.events) == 0 {
+		return true
+	}
+
+	if result.Event == nil {
+		return false
+	}
+
+	for _, event := range s.events {
+		if event == result.Event.Name || strings.Replace(event, " ", "", -1) == result.Event.Signature {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newSink(config sinkConfig) (*filteredSink, error) {
+	retries := defaultSinkRetries
+	if config.Retries != nil {
+		retries = *config.Retries
+	}
+
+	sink := &filteredSink{events: config.Events}
+
+	switch config.Type {
+	case "webhook":
+		if config.URL == "" {
+			return nil, errors.New("webhook sink without url")
+		}
+		sink.name = config.URL
+		sink.sink = &webhookSink{
+			url:     config.URL,
+			retries: retries,
+			client:  &http.Client{Timeout: sinkTimeout},
+		}
+
+	case "exec":
+		if config.Command == "" {
+			return nil, errors.New("exec sink without command")
+		}
+		sink.name = config.Command
+		sink.sink = &execSink{command: config.Command}
+
+	case "file":
+		if config.Path == "" {
+			return nil, errors.New("file sink without path")
+		}
+		sink.name = expandHome(config.Path)
+		sink.sink = &fileSink{path: sink.name}
+
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", config.Type)
+	}
+
+	for _, input := range config.Addresses {
+		address, err := resolveContract(input)
+		if err != nil {
+			return nil, err
+		}
+		sink.addresses = append(sink.addresses, address)
+	}
+
+	return sink, nil
+}
+
+// loadSinks creates the sinks of the config file and of the --webhook,
+// --exec and --sink-file flags. Sinks from flags receive every event.
+func loadSinks(c *cli.Context) ([]*filteredSink, error) {
+	configs := []sinkConfig{}
+
+	if viper.IsSet("sinks") {
+		if err := viper.UnmarshalKey("sinks", &configs); err != nil {
+			return nil, fmt.Errorf("sinks: %s", err)
+		}
+	}
+
+	for _, url := range c.StringSlice("webhook") {
+		configs = append(configs, sinkConfig{Type: "webhook", URL: url})
+	}
+	for _, command := range c.StringSlice("exec") {
+		configs = append(configs, sinkConfig{Type: "exec", Command: command})
+	}
+	for _, path := range c.StringSlice("sink-file") {
+		configs = append(configs, sinkConfig{Type: "file", Path: path})
+	}
+
+	sinks := []*filteredSink{}
+
+	for i, config := range configs {
+		sink, err := newSink(config)
+		if err != nil {
+			return nil, fmt.Errorf("sink %d: %s", i+1, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	for _, sink := range sinks {
+		sink.start()
+	}
+
+	return sinks, nil
+}
+
+// closeSinks waits until the sinks have delivered their queued events.
+func closeSinks(sinks []*filteredSink) {
+	for _, sink := range sinks {
+		sink.stop()
+	}
+}
+
+// dispatchEvent queues an event for the sinks it matches. Failing sinks are
+// reported and do not stop the others.
+func dispatchEvent(sinks []*filteredSink, result *logResult) error {
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	for _, sink := range sinks {
+		if !sink.matches(result) {
+			continue
+		}
+
+		sink.enqueue(payload)
+	}
+
+	return nil
+}
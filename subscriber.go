@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// logKey identifies a log in a block. A log included again in another block
+// after a reorganization has a different key.
+type logKey struct {
+	blockHash common.Hash
+	txHash    common.Hash
+	index     uint
+}
+
+func newLogKey(log *types.Log) logKey {
+	return logKey{blockHash: log.BlockHash, txHash: log.TxHash, index: log.Index}
+}
+
+// handlerError is returned when handling a log fails, which stops the
+// subscriber instead of reconnecting.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// logSubscriber follows the logs of a query. When the subscription fails it
+// reconnects with a growing delay and backfills the blocks it missed with
+// FilterLogs. Over transports without subscriptions, such as HTTP, it polls
+// instead. Logs are delivered once, and delivered logs which are dropped by
+// a reorganization are delivered again with Removed set.
+type logSubscriber struct {
+	query      wanchain.FilterQuery
+	fromBlock  *uint64
+	reorgDepth uint64
+	interval   time.Duration
+	handle     func(*types.Log) error
+
+	quit      chan struct{}
+	started   bool
+	floor     uint64
+	lastBlock uint64
+	seen      map[logKey]types.Log
+}
+
+func newLogSubscriber(query wanchain.FilterQuery, reorgDepth uint64, interval time.Duration, handle func(*types.Log) error) *logSubscriber {
+	query.FromBlock = nil
+	query.ToBlock = nil
+
+	return &logSubscriber{
+		query:      query,
+		reorgDepth: reorgDepth,
+		interval:   interval,
+		handle:     handle,
+		quit:       make(chan struct{}),
+		seen:       map[logKey]types.Log{},
+	}
+}
+
+// stop makes run return after the log being delivered, if any.
+func (s *logSubscriber) stop() {
+	close(s.quit)
+}
+
+// run follows the logs until handling a log fails or the subscriber is
+// stopped.
+func (s *logSubscriber) run() error {
+	delay := minReconnectDelay
+
+	for {
+		connected, err := s.follow()
+
+		if handleErr, ok := err.(*handlerError); ok {
+			return handleErr.err
+		}
+
+		select {
+		case <-s.quit:
+			return nil
+		default:
+		}
+
+		if connected {
+			delay = minReconnectDelay
+		}
+
+		fmt.Fprintf(os.Stderr, "Subscription failed: %s, reconnecting in %s\n", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-s.quit:
+			return nil
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// follow connects, subscribes and delivers logs until the subscription
+// fails. The subscription is made before the backfill, so no block falls
+// between the two; logs found by both are only delivered once.
+func (s *logSubscriber) follow() (bool, error) {
+	rpcClient, err := rpc.Dial(viper.GetString("nodeuri"))
+	if err != nil {
+		return false, err
+	}
+	defer rpcClient.Close()
+
+	client := wanclient.NewClient(rpcClient)
+	logs := make(chan types.Log, 256)
+
+	sub, err := client.SubscribeFilterLogs(context.Background(), s.query, logs)
+	if err == rpc.ErrNotificationsUnsupported {
+		return s.poll(rpcClient, client)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer sub.Unsubscribe()
+
+	if err := s.backfill(client); err != nil {
+		return true, err
+	}
+
+	for {
+		select {
+		case <-s.quit:
+			return true, nil
+
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return true, err
+
+		case log := <-logs:
+			if err := s.deliver(&log); err != nil {
+				return true, err
+			}
+		}
+	}
+}
+
+// poll follows the logs with a log filter, made before the backfill like the
+// subscription. Nodes without filters, such as some load balanced ones, are
+// polled by backfilling the new blocks instead.
+func (s *logSubscriber) poll(rpcClient *rpc.Client, client *wanclient.Client) (bool, error) {
+	ctx := context.Background()
+
+	var filterID string
+	err := rpcClient.CallContext(ctx, &filterID, "eth_newFilter", toFilterArg(s.query))
+
+	useFilter := err == nil
+	if useFilter {
+		defer rpcClient.CallContext(ctx, nil, "eth_uninstallFilter", filterID)
+	} else {
+		fmt.Fprintf(os.Stderr, "Log filters are not available (%s), polling blocks\n", err)
+	}
+
+	if err := s.backfill(client); err != nil {
+		return false, err
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return true, nil
+		}
+
+		if !useFilter {
+			if err := s.backfill(client); err != nil {
+				return true, err
+			}
+			continue
+		}
+
+		var logs []types.Log
+		if err := rpcClient.CallContext(ctx, &logs, "eth_getFilterChanges", filterID); err != nil {
+			// an expired filter is replaced after reconnecting
+			return true, err
+		}
+
+		for i := range logs {
+			if err := s.deliver(&logs[i]); err != nil {
+				return true, err
+			}
+		}
+	}
+}
+
+// toFilterArg converts a query to the parameter of eth_newFilter.
+func toFilterArg(q wanchain.FilterQuery) map[string]interface{} {
+	arg := map[string]interface{}{
+		"address": q.Addresses,
+		"topics":  q.Topics,
+	}
+
+	if q.FromBlock != nil {
+		arg["fromBlock"] = hexutil.EncodeBig(q.FromBlock)
+	}
+	if q.ToBlock != nil {
+		arg["toBlock"] = hexutil.EncodeBig(q.ToBlock)
+	}
+
+	return arg
+}
+
+// backfill queries the logs from reorgDepth blocks before the last seen
+// block up to the head, but never from before the first block the command
+// covers, so logs emitted before it started are not delivered as new. Logs
+// which were not delivered yet are delivered, and delivered logs which are
+// no longer in the chain are retracted.
+func (s *logSubscriber) backfill(client *wanclient.Client) error {
+	head, err := currentBlockNumber(client)
+	if err != nil {
+		return err
+	}
+
+	from, ok := s.backfillStart(head.Uint64())
+	if !ok {
+		return nil
+	}
+
+	found := map[logKey]bool{}
+
+	err = filterLogPages(client, s.query, from, head.Uint64(), 5000, func(logs []types.Log) error {
+		for i := range logs {
+			found[newLogKey(&logs[i])] = true
+
+			if err := s.deliver(&logs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	removed := []types.Log{}
+	for key, log := range s.seen {
+		if log.BlockNumber >= from && log.BlockNumber <= head.Uint64() && !found[key] {
+			removed = append(removed, log)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].BlockNumber != removed[j].BlockNumber {
+			return removed[i].BlockNumber < removed[j].BlockNumber
+		}
+		return removed[i].Index < removed[j].Index
+	})
+
+	for i := range removed {
+		removed[i].Removed = true
+
+		if err := s.deliver(&removed[i]); err != nil {
+			return err
+		}
+	}
+
+	// the next backfill starts from here, even without logs
+	if head.Uint64() > s.lastBlock {
+		s.lastBlock = head.Uint64()
+		s.prune()
+	}
+
+	return nil
+}
+
+// backfillStart returns the first block to backfill up to the head, and
+// false when there is nothing to backfill. The first call records the first
+// block the command covers: the block given with -b, or the block after the
+// head when the command starts.
+func (s *logSubscriber) backfillStart(head uint64) (uint64, bool) {
+	if !s.started {
+		s.started = true
+
+		if s.fromBlock == nil {
+			// nothing to catch up on the first connection
+			s.floor = head + 1
+			s.lastBlock = head
+			return 0, false
+		}
+
+		s.floor = *s.fromBlock
+	}
+
+	from := s.floor
+	if s.lastBlock > s.reorgDepth && s.lastBlock-s.reorgDepth > from {
+		from = s.lastBlock - s.reorgDepth
+	}
+
+	return from, from <= head
+}
+
+// deliver hands a log to the handler unless it was delivered already, and
+// only retracts logs which were delivered.
+func (s *logSubscriber) deliver(log *types.Log) error {
+	key := newLogKey(log)
+	_, delivered := s.seen[key]
+
+	if log.Removed {
+		if !delivered {
+			return nil
+		}
+		delete(s.seen, key)
+	} else {
+		if delivered {
+			return nil
+		}
+		s.seen[key] = *log
+
+		if log.BlockNumber > s.lastBlock {
+			s.lastBlock = log.BlockNumber
+			s.prune()
+		}
+	}
+
+	if err := s.handle(log); err != nil {
+		return &handlerError{err}
+	}
+
+	return nil
+}
+
+// prune forgets logs too old to be dropped by a reorganization.
+func (s *logSubscriber) prune() {
+	if s.lastBlock <= s.reorgDepth {
+		return
+	}
+
+	for key, log := range s.seen {
+		if log.BlockNumber < s.lastBlock-s.reorgDepth {
+			delete(s.seen, key)
+		}
+	}
+}
+
+func subscribe(c *cli.Context) error {
+	registry, err := loadAbiRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	query, err := buildLogQuery(c, registry)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if len(query.Addresses) == 0 && len(query.Topics) == 0 {
+		return cli.NewExitError("No address or event provided", 1)
+	}
+
+	sinks, err := loadSinks(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	w := newRecordWriter()
+
+	subscriber := newLogSubscriber(query, uint64(c.Int("reorg-depth")), c.Duration("interval"), func(log *types.Log) error {
+		result := newLogResult(log)
+		if event := registry.event(log); event != nil {
+			result.Event = newDecodedEvent(event, log)
+		}
+
+		if err := w.write(result); err != nil {
+			return err
+		}
+
+		return dispatchEvent(sinks, result)
+	})
+
+	if c.IsSet("block") {
+		fromBlock := uint64(c.Int64("block"))
+		subscriber.fromBlock = &fromBlock
+	}
+
+	// on Ctrl-C, stop following and deliver the events queued for the
+	// sinks; a second Ctrl-C exits at once
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "Stopping, delivering the queued events")
+		subscriber.stop()
+	}()
+
+	err = subscriber.run()
+	w.close()
+	closeSinks(sinks)
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
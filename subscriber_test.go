@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	wanchain "github.com/wanchain/go-wanchain"
+)
+
+func TestBackfillStart(t *testing.T) {
+	type call struct {
+		head     uint64
+		lastSeen uint64 // lastBlock after the previous call, 0 to keep it
+		from     uint64
+		ok       bool
+	}
+
+	fromBlock := uint64(900)
+
+	tests := []struct {
+		name      string
+		fromBlock *uint64
+		calls     []call
+	}{
+		{
+			name: "started at the head",
+			calls: []call{
+				{head: 1000, ok: false},
+				// near the start the reorg window reaches before it
+				{head: 1001, lastSeen: 1000, from: 1001, ok: true},
+				{head: 1005, lastSeen: 1005, from: 1001, ok: true},
+				// after the reorg window
+				{head: 1030, lastSeen: 1020, from: 1008, ok: true},
+			},
+		},
+		{
+			name: "no new block yet",
+			calls: []call{
+				{head: 1000, ok: false},
+				{head: 1000, from: 1001, ok: false},
+			},
+		},
+		{
+			name:      "started from a block",
+			fromBlock: &fromBlock,
+			calls: []call{
+				{head: 1000, from: 900, ok: true},
+				{head: 1000, lastSeen: 905, from: 900, ok: true},
+				{head: 1000, lastSeen: 1000, from: 988, ok: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s := newLogSubscriber(wanchain.FilterQuery{}, 12, 0, nil)
+		s.fromBlock = test.fromBlock
+
+		for i, call := range test.calls {
+			if call.lastSeen != 0 {
+				s.lastBlock = call.lastSeen
+			}
+
+			from, ok := s.backfillStart(call.head)
+			if ok != call.ok || (ok && from != call.from) {
+				t.Errorf("%s, call %d: got %d, %t, want %d, %t", test.name, i+1, from, ok, call.from, call.ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/jsgoyette/wanutil/contracts"
+
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+)
+
+// tokenInfo is the metadata of a token contract.
+type tokenInfo struct {
+	Address     common.Address `json:"address"`
+	Symbol      string         `json:"symbol"`
+	Name        string         `json:"name"`
+	Decimals    uint8          `json:"decimals"`
+	TotalSupply string         `json:"totalSupply"`
+}
+
+func (t *tokenInfo) printText() {
+	fmt.Printf("Address: %s\n", t.Address.Hex())
+	fmt.Printf("Name: %s\n", t.Name)
+	fmt.Printf("Symbol: %s\n", t.Symbol)
+	fmt.Printf("Decimals: %d\n", t.Decimals)
+	fmt.Printf("Total Supply: %s (%s)\n\n", t.TotalSupply, t.formatTotalSupply())
+}
+
+func (t *tokenInfo) csvHeader() []string {
+	return []string{"address", "symbol", "name", "decimals", "totalSupply"}
+}
+
+func (t *tokenInfo) csvRecord() []string {
+	return []string{
+		t.Address.Hex(),
+		t.Symbol,
+		t.Name,
+		strconv.Itoa(int(t.Decimals)),
+		t.TotalSupply,
+	}
+}
+
+func (t *tokenInfo) formatTotalSupply() string {
+	supply, ok := parseBig256(t.TotalSupply)
+	if !ok {
+		return t.TotalSupply
+	}
+	return formatUnits(supply, t.Decimals)
+}
+
+// tokenRegistry caches token metadata, so the contract is only asked once.
+// Each network profile has its own file under ~/.wanutil/tokens.
+type tokenRegistry struct {
+	Tokens map[string]*tokenInfo `json:"tokens"`
+
+	path string
+}
+
+func loadTokenRegistry() (*tokenRegistry, error) {
+	network := activeNetwork
+	if network == "" {
+		network = "default"
+	}
+
+	path, err := getWanutilPath("tokens", network+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &tokenRegistry{
+		Tokens: map[string]*tokenInfo{},
+		path:   path,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, err
+	}
+
+	if registry.Tokens == nil {
+		registry.Tokens = map[string]*tokenInfo{}
+	}
+
+	return registry, nil
+}
+
+func (r *tokenRegistry) save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, data, 0600)
+}
+
+func (r *tokenRegistry) get(address common.Address) *tokenInfo {
+	return r.Tokens[strings.ToLower(address.Hex())]
+}
+
+func (r *tokenRegistry) put(token *tokenInfo) {
+	r.Tokens[strings.ToLower(token.Address.Hex())] = token
+}
+
+func (r *tokenRegistry) findSymbol(symbol string) *tokenInfo {
+	for _, token := range r.Tokens {
+		if strings.EqualFold(token.Symbol, symbol) {
+			return token
+		}
+	}
+	return nil
+}
+
+// sorted returns the tokens ordered by symbol.
+func (r *tokenRegistry) sorted() []*tokenInfo {
+	tokens := make([]*tokenInfo, 0, len(r.Tokens))
+	for _, token := range r.Tokens {
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return strings.ToLower(tokens[i].Symbol) < strings.ToLower(tokens[j].Symbol)
+	})
+
+	return tokens
+}
+
+func fetchTokenInfo(client *wanclient.Client, address common.Address) (*tokenInfo, error) {
+	instance, err := contracts.NewStandard(address, client)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.CallOpts{Context: context.Background()}
+
+	decimals, err := instance.Decimals(opts)
+	if err != nil {
+		return nil, fmt.Errorf("decimals: %s", err)
+	}
+
+	symbol, err := instance.Symbol(opts)
+	if err != nil {
+		return nil, fmt.Errorf("symbol: %s", err)
+	}
+
+	name, err := instance.Name(opts)
+	if err != nil {
+		return nil, fmt.Errorf("name: %s", err)
+	}
+
+	totalSupply, err := instance.TotalSupply(opts)
+	if err != nil {
+		return nil, fmt.Errorf("totalSupply: %s", err)
+	}
+
+	return &tokenInfo{
+		Address:     address,
+		Symbol:      symbol,
+		Name:        name,
+		Decimals:    decimals,
+		TotalSupply: totalSupply.String(),
+	}, nil
+}
+
+// resolveToken finds a token by symbol or address. Symbols are looked up in
+// the config contracts first and then in the registry. Tokens missing from
+// the registry are fetched from the contract and cached.
+func resolveToken(client *wanclient.Client, registry *tokenRegistry, token string) (*tokenInfo, error) {
+	var address common.Address
+
+	contract, configured, err := getConfigContract(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if configured {
+		address = contract.Address
+	} else if common.IsHexAddress(token) {
+		address = common.HexToAddress(token)
+	} else if info := registry.findSymbol(token); info != nil {
+		return info, nil
+	} else {
+		return nil, fmt.Errorf("Token not found: %s", token)
+	}
+
+	if info := registry.get(address); info != nil {
+		return info, nil
+	}
+
+	info, err := fetchTokenInfo(client, address)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.put(info)
+
+	if err := registry.save(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func getTokenInfo(c *cli.Context) error {
+	token := c.String("token")
+	if token == "" {
+		token = c.String("address")
+	}
+
+	if token == "" {
+		return cli.NewExitError("No token symbol or address provided", 1)
+	}
+
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	client := getWanchainConnection()
+
+	info, err := resolveToken(client, registry, token)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	// refresh the total supply and any other changes from the contract
+	info, err = fetchTokenInfo(client, info.Address)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if cached := registry.get(info.Address); cached != nil && cached.Symbol != info.Symbol {
+		info.Symbol = cached.Symbol
+	}
+
+	registry.put(info)
+
+	if err := registry.save(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := printRecord(info); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func addToken(c *cli.Context) error {
+	address := c.String("address")
+
+	if address == "" {
+		return cli.NewExitError("No address provided", 1)
+	}
+	if !common.IsHexAddress(address) {
+		return cli.NewExitError("Invalid address", 1)
+	}
+
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	client := getWanchainConnection()
+
+	info, err := fetchTokenInfo(client, common.HexToAddress(address))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	// allow a local symbol when the contract symbol is ambiguous
+	if symbol := c.String("symbol"); symbol != "" {
+		info.Symbol = symbol
+	}
+
+	registry.put(info)
+
+	if err := registry.save(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := printRecord(info); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func listTokens(c *cli.Context) error {
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	w := newRecordWriter()
+
+	for _, info := range registry.sorted() {
+		if err := w.write(info); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+type allowanceResult struct {
+	Token     string         `json:"token"`
+	Owner     common.Address `json:"owner"`
+	Spender   common.Address `json:"spender"`
+	Allowance string         `json:"allowance"`
+	Formatted string         `json:"formatted"`
+}
+
+func (r *allowanceResult) printText() {
+	fmt.Printf("%s allowance of %s for %s: %s (%s)\n", r.Token, r.Owner.Hex(), r.Spender.Hex(), r.Allowance, r.Formatted)
+}
+
+func (r *allowanceResult) csvHeader() []string {
+	return []string{"token", "owner", "spender", "allowance", "formatted"}
+}
+
+func (r *allowanceResult) csvRecord() []string {
+	return []string{r.Token, r.Owner.Hex(), r.Spender.Hex(), r.Allowance, r.Formatted}
+}
+
+func getTokenAllowance(c *cli.Context) error {
+	tokenName := c.String("token")
+	owner := c.String("owner")
+	spender := c.String("spender")
+
+	if tokenName == "" {
+		return cli.NewExitError("No token provided", 1)
+	}
+	if owner == "" || spender == "" {
+		return cli.NewExitError("Both owner and spender must be provided", 1)
+	}
+
+	ownerAddress, _, err := resolveAddress(owner)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	spenderAddress, _, err := resolveAddress(spender)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	client := getWanchainConnection()
+
+	token, err := resolveToken(client, registry, tokenName)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	instance, err := contracts.NewStandard(token.Address, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	allowance, err := instance.Allowance(&bind.CallOpts{Context: context.Background()}, ownerAddress, spenderAddress)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result := &allowanceResult{
+		Token:     token.Symbol,
+		Owner:     ownerAddress,
+		Spender:   spenderAddress,
+		Allowance: allowance.String(),
+		Formatted: formatUnits(allowance, token.Decimals),
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func transferToken(c *cli.Context) error {
+	return sendTokenTransaction(c, "transfer", c.String("to"))
+}
+
+func approveToken(c *cli.Context) error {
+	return sendTokenTransaction(c, "approve", c.String("spender"))
+}
+
+// sendTokenTransaction signs and sends a transfer or approve transaction
+// for a token amount given in whole tokens.
+func sendTokenTransaction(c *cli.Context, method string, target string) error {
+	tokenName := c.String("token")
+	amount := c.String("amount")
+
+	if tokenName == "" {
+		return cli.NewExitError("No token provided", 1)
+	}
+	if target == "" {
+		return cli.NewExitError("No recipient or spender provided", 1)
+	}
+	if amount == "" {
+		return cli.NewExitError("No amount provided", 1)
+	}
+
+	targetAddress, _, err := resolveAddress(target)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	key, err := loadPrivateKey(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	params, err := getTxParams(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	registry, err := loadTokenRegistry()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	client := getWanchainConnection()
+
+	token, err := resolveToken(client, registry, tokenName)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	value, err := parseUnits(amount, token.Decimals)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	chainID, err := getSigningChainID(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	instance, err := contracts.NewStandard(token.Address, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	opts := newTransactOpts(key, chainID, params)
+
+	var tx *types.Transaction
+
+	if method == "transfer" {
+		tx, err = instance.Transfer(opts, targetAddress, value)
+	} else {
+		tx, err = instance.Approve(opts, targetAddress, value)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result, err := newSignedTxResult(tx, opts.From)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result.Sent = true
+
+	var waitErr error
+	if c.Bool("wait") {
+		_, waitErr = result.waitForReceipt(client, tx)
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if waitErr != nil {
+		return cli.NewExitError(waitErr.Error(), 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// NORMAL_TX is the Wanchain transaction type of plain transactions.
+const NORMAL_TX = 1
+
+// txParams are the fields of a transaction to build. The nonce, gas limit
+// and gas price are looked up from the node when not set.
+type txParams struct {
+	from     common.Address
+	to       *common.Address
+	value    *big.Int
+	data     []byte
+	txType   uint64
+	nonce    *uint64
+	gasLimit *big.Int
+	gasPrice *big.Int
+}
+
+// getTxParams reads the gas, gas price, nonce and transaction type flags.
+func getTxParams(c *cli.Context) (*txParams, error) {
+	params := &txParams{
+		value:  new(big.Int),
+		txType: NORMAL_TX,
+	}
+
+	// not every command has the --txtype flag
+	if c.IsSet("txtype") {
+		params.txType = uint64(c.Int("txtype"))
+	}
+
+	if c.IsSet("nonce") {
+		nonce := uint64(c.Int64("nonce"))
+		params.nonce = &nonce
+	}
+
+	if c.IsSet("gas") {
+		params.gasLimit = big.NewInt(c.Int64("gas"))
+	}
+
+	if gasPrice := c.String("gas-price"); gasPrice != "" {
+		price, ok := parseBig256(gasPrice)
+		if !ok {
+			return nil, fmt.Errorf("Invalid gas price: %s", gasPrice)
+		}
+		params.gasPrice = price
+	}
+
+	return params, nil
+}
+
+// fill looks up the missing nonce, gas price and gas limit. Without a
+// client, for offline signing, all of them must already be set.
+func (p *txParams) fill(client *wanclient.Client) error {
+	if client == nil {
+		if p.nonce == nil || p.gasLimit == nil || p.gasPrice == nil {
+			return errors.New("Nonce, gas and gas price are required for offline signing")
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+
+	if p.nonce == nil {
+		nonce, err := client.PendingNonceAt(ctx, p.from)
+		if err != nil {
+			return err
+		}
+		p.nonce = &nonce
+	}
+
+	if p.gasPrice == nil {
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return err
+		}
+		p.gasPrice = gasPrice
+	}
+
+	if p.gasLimit == nil {
+		gasLimit, err := client.EstimateGas(ctx, wanchain.CallMsg{
+			From:     p.from,
+			To:       p.to,
+			GasPrice: p.gasPrice,
+			Value:    p.value,
+			Data:     p.data,
+		})
+		if err != nil {
+			return fmt.Errorf("gas estimation failed: %s", err)
+		}
+		p.gasLimit = gasLimit
+	}
+
+	return nil
+}
+
+func (p *txParams) sign(key *ecdsa.PrivateKey, chainID *big.Int) (*types.Transaction, error) {
+	tx, err := newWanTransaction(p.txType, *p.nonce, p.to, p.value, p.gasLimit, p.gasPrice, p.data)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+}
+
+// newWanTransaction creates an unsigned transaction of any Wanchain
+// transaction type. The go-wanchain constructors only create normal
+// transactions, so the transaction is decoded from its RLP fields instead.
+func newWanTransaction(txType uint64, nonce uint64, to *common.Address, value, gasLimit, gasPrice *big.Int, data []byte) (*types.Transaction, error) {
+	fields := []interface{}{
+		txType,
+		nonce,
+		gasPrice,
+		gasLimit,
+		to,
+		value,
+		data,
+		new(big.Int), // V
+		new(big.Int), // R
+		new(big.Int), // S
+	}
+
+	raw, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// newTransactOpts creates the options for the contract bindings. The
+// transaction is signed for the given chain, whatever signer the binding
+// passes in.
+func newTransactOpts(key *ecdsa.PrivateKey, chainID *big.Int, params *txParams) *bind.TransactOpts {
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	opts := &bind.TransactOpts{
+		From: from,
+		Signer: func(_ types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+		},
+		GasPrice: params.gasPrice,
+		GasLimit: params.gasLimit,
+		Context:  context.Background(),
+	}
+
+	if params.nonce != nil {
+		opts.Nonce = new(big.Int).SetUint64(*params.nonce)
+	}
+
+	return opts
+}
+
+// getSigningChainID returns the chain ID from --chain-id or the selected
+// network. Offline signing never asks the node.
+func getSigningChainID(c *cli.Context, client *wanclient.Client) (*big.Int, error) {
+	if c.IsSet("chain-id") {
+		return big.NewInt(c.Int64("chain-id")), nil
+	}
+
+	if client == nil && viper.GetInt64("chainid") == 0 {
+		return nil, errors.New("Chain ID is required for offline signing, use --chain-id or set chainid in your config file")
+	}
+
+	return getChainID(client)
+}
+
+// signedTxResult is a signed transaction with its raw RLP encoding, and its
+// receipt and decoded events when the command waited for it to be mined.
+type signedTxResult struct {
+	Transaction *transactionResult `json:"transaction"`
+	Raw         hexutil.Bytes      `json:"raw"`
+	Sent        bool               `json:"sent"`
+	Receipt     *receiptResult     `json:"receipt,omitempty"`
+	Events      []*decodedEvent    `json:"events,omitempty"`
+}
+
+func newSignedTxResult(tx *types.Transaction, from common.Address) (*signedTxResult, error) {
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signedTxResult{
+		Transaction: newTransactionResult(tx, from.Hex(), true),
+		Raw:         raw,
+	}, nil
+}
+
+func (r *signedTxResult) printText() {
+	r.Transaction.printText()
+	fmt.Printf("Raw: %s\n", hexutil.Encode(r.Raw))
+
+	if r.Sent {
+		fmt.Printf("Sent: %s\n", r.Transaction.Hash.Hex())
+	}
+
+	if r.Receipt != nil {
+		fmt.Println()
+		r.Receipt.printText()
+	}
+
+	for _, event := range r.Events {
+		event.printText()
+	}
+}
+
+func (r *signedTxResult) csvHeader() []string {
+	return append(r.Transaction.csvHeader(), "raw", "sent", "status")
+}
+
+func (r *signedTxResult) csvRecord() []string {
+	status := ""
+	if r.Receipt != nil {
+		status = fmt.Sprint(r.Receipt.Status)
+	}
+
+	return append(r.Transaction.csvRecord(), hexutil.Encode(r.Raw), fmt.Sprint(r.Sent), status)
+}
+
+// waitForReceipt waits until the transaction is mined and adds the receipt.
+// It returns an error along with the receipt when the transaction failed.
+func (r *signedTxResult) waitForReceipt(client *wanclient.Client, tx *types.Transaction) (*types.Receipt, error) {
+	receipt, err := bind.WaitMined(context.Background(), client, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Receipt = newReceiptResult(receipt)
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		return receipt, fmt.Errorf("Transaction %s failed", tx.Hash().Hex())
+	}
+
+	return receipt, nil
+}
+
+func sendTransaction(c *cli.Context) error {
+	offline := c.Bool("offline")
+
+	if offline && c.Bool("broadcast") {
+		return cli.NewExitError("Ambiguous: an offline transaction cannot be broadcast", 1)
+	}
+
+	key, err := loadPrivateKey(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	params, err := getTxParams(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	params.from = crypto.PubkeyToAddress(key.PublicKey)
+
+	if to := c.String("to"); to != "" {
+		address, _, err := resolveAddress(to)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		params.to = &address
+	}
+
+	if value := c.String("value"); value != "" {
+		params.value, err = parseUnits(value, 18)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	if data := c.String("data"); data != "" {
+		params.data, err = hex.DecodeString(strings.TrimPrefix(data, "0x"))
+		if err != nil {
+			return cli.NewExitError("Invalid data: "+err.Error(), 1)
+		}
+	}
+
+	if params.to == nil && len(params.data) == 0 {
+		return cli.NewExitError("No recipient or contract code provided", 1)
+	}
+
+	var client *wanclient.Client
+	if !offline {
+		client = getWanchainConnection()
+	}
+
+	if err := params.fill(client); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	chainID, err := getSigningChainID(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	tx, err := params.sign(key, chainID)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	result, err := newSignedTxResult(tx, params.from)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if c.Bool("broadcast") {
+		if err := client.SendTransaction(context.Background(), tx); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		result.Sent = true
+	}
+
+	if err := printRecord(result); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
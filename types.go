@@ -1,17 +1,36 @@
 package main
 
-import (
-	"github.com/wanchain/go-wanchain/accounts/abi"
-)
+type AbiArgument struct {
+	Name    string
+	Type    *AbiType
+	Indexed bool
+}
 
 type AbiField struct {
-	Type      string
-	Name      string
-	Constant  bool
-	Indexed   bool
-	Anonymous bool
-	Inputs    []abi.Argument
-	Outputs   []abi.Argument
+	Type            string
+	Name            string
+	Constant        bool
+	Payable         *bool
+	StateMutability string
+	Indexed         bool
+	Anonymous       bool
+	Inputs          []AbiArgument
+	Outputs         []AbiArgument
+}
+
+// isReadOnly tells whether the method does not modify the state, from its
+// stateMutability or the constant field of older ABIs.
+func (f *AbiField) isReadOnly() bool {
+	return f.Constant || f.StateMutability == "view" || f.StateMutability == "pure"
+}
+
+// isNonPayable tells whether the method is known to reject a value. Methods
+// known only by their signature may be payable.
+func (f *AbiField) isNonPayable() bool {
+	if f.StateMutability != "" {
+		return f.StateMutability != "payable"
+	}
+	return f.Payable != nil && !*f.Payable
 }
 
 type AbiMethod struct {
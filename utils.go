@@ -2,19 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math/big"
+	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/wanchain/go-wanchain/accounts/abi"
-	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/crypto"
 	wanclient "github.com/wanchain/go-wanchain/ethclient"
+	"github.com/wanchain/go-wanchain/rpc"
 	// "github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/spf13/viper"
@@ -31,6 +31,17 @@ func getWanchainConnection() *wanclient.Client {
 	return client
 }
 
+func getRpcConnection() *rpc.Client {
+	uri := viper.GetString("nodeuri")
+
+	client, err := rpc.Dial(uri)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return client
+}
+
 // func getEthereumConnection() *ethclient.Client {
 //	uri := viper.GetString("nodeuri")
 
@@ -42,7 +53,113 @@ func getWanchainConnection() *wanclient.Client {
 //	return client
 // }
 
+// batchCallRPC sends the requests in batches of size, or one by one when
+// batching is disabled with a size of 1 or less.
+func batchCallRPC(ctx context.Context, client *rpc.Client, elems []rpc.BatchElem, size int) error {
+	if size < 1 {
+		size = 1
+	}
+
+	for start := 0; start < len(elems); start += size {
+		end := start + size
+		if end > len(elems) {
+			end = len(elems)
+		}
+
+		batch := elems[start:end]
+
+		if len(batch) == 1 {
+			batch[0].Error = client.CallContext(ctx, batch[0].Result, batch[0].Method, batch[0].Args...)
+		} else if err := client.BatchCallContext(ctx, batch); err != nil {
+			return err
+		}
+
+		for _, elem := range batch {
+			if elem.Error != nil {
+				return elem.Error
+			}
+		}
+	}
+
+	return nil
+}
+
+// getWanutilPath returns a path inside the ~/.wanutil directory.
+func getWanutilPath(elem ...string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(append([]string{home, ".wanutil"}, elem...)...), nil
+}
+
+// resolveAddress accepts an address, a label from the config address book
+// or an account label, and returns the address with its label.
+func resolveAddress(input string) (common.Address, string, error) {
+	if common.IsHexAddress(input) {
+		return common.HexToAddress(input), "", nil
+	}
+
+	if address := viper.GetString("addressbook." + input); common.IsHexAddress(address) {
+		return common.HexToAddress(address), input, nil
+	}
+
+	labels, err := loadAccountLabels()
+	if err != nil {
+		return common.Address{}, "", err
+	}
+
+	if address, ok := labels.Labels[input]; ok {
+		return address, input, nil
+	}
+
+	return common.Address{}, "", fmt.Errorf("Unknown address or label: %s", input)
+}
+
+// getChainID returns the chain ID of the selected network, asking the node
+// only when it is not set in the config.
+func getChainID(client *wanclient.Client) (*big.Int, error) {
+	if viper.GetInt64("chainid") != 0 {
+		return big.NewInt(viper.GetInt64("chainid")), nil
+	}
+
+	if client == nil {
+		client = getWanchainConnection()
+	}
+
+	return client.NetworkID(context.Background())
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// resolveAbiFile looks up relative ABI file names in the abidir of the
+// selected network when they do not exist in the working directory.
+func resolveAbiFile(abiFileName string) string {
+	abiDir := viper.GetString("abidir")
+
+	if abiDir == "" || filepath.IsAbs(abiFileName) {
+		return abiFileName
+	}
+
+	if _, err := os.Stat(abiFileName); err == nil {
+		return abiFileName
+	}
+
+	return filepath.Join(expandHome(abiDir), abiFileName)
+}
+
 func parseAbi(abiFileName string) ([]AbiField, error) {
+	abiFileName = resolveAbiFile(abiFileName)
+
 	abiBytes, err := ioutil.ReadFile(abiFileName)
 	if err != nil {
 		return nil, err
@@ -88,145 +205,74 @@ func fromWei(i *big.Int) *big.Float {
 	return new(big.Float).Quo(f, w)
 }
 
-func currentBlockNumber(client *wanclient.Client) (*big.Int, error) {
-	latestBlock, err := client.BlockByNumber(context.Background(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return latestBlock.Number(), nil
-}
+// formatUnits formats an integer amount of the smallest unit as a decimal
+// number with the given decimals, without rounding.
+func formatUnits(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+	places := int(decimals)
 
-func getInputNamesString(inputs []abi.Argument) string {
-	inputNames := make([]string, len(inputs))
-	for i, input := range inputs {
-		inputNames[i] = input.Name
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
 	}
-	return strings.Join(inputNames, ", ")
-}
-
-func printTransaction(tx *types.Transaction, from string, isPending bool) {
-	v, r, s := tx.RawSignatureValues()
 
-	fmt.Printf("Hash: %s\n", tx.Hash().Hex())
-	if tx.To() != nil {
-		fmt.Printf("To: %s\n", tx.To().Hex())
+	str := digits[:len(digits)-places]
+	if frac := strings.TrimRight(digits[len(digits)-places:], "0"); frac != "" {
+		str += "." + frac
 	}
-	fmt.Printf("From: %s\n", from)
-	fmt.Printf("TxType: 0x%x\n", tx.Txtype())
-	fmt.Printf("Value: %s\n", tx.Value().String())
-	fmt.Printf("Gas: %s\n", tx.Gas())
-	fmt.Printf("Gas Price: %d\n", tx.GasPrice().Uint64())
-	fmt.Printf("Nonce: %d\n", tx.Nonce())
-	fmt.Printf("Size: %s\n", tx.Size().String())
 
-	data := tx.Data()
-
-	if len(data) >= 4 {
-		fmt.Printf("Data: %x\n", data[:4])
-
-		b := 4
-		for len(data) >= b+32 {
-			fmt.Printf("      %x\n", data[b:b+32])
-			b = b + 32
-		}
-
-		if len(data) > b {
-			fmt.Printf("      %x\n", data[b:])
-		}
-	} else {
-		fmt.Printf("Data: %x\n", tx.Data())
+	if amount.Sign() < 0 {
+		str = "-" + str
 	}
 
-	fmt.Printf("V: 0x%x\n", v)
-	fmt.Printf("R: 0x%x\n", r)
-	fmt.Printf("S: 0x%x\n\n", s)
-	fmt.Printf("Pending: %v\n\n", isPending)
+	return str
 }
 
-func printReceipt(receipt *types.Receipt) {
-	fmt.Printf("Status: %d\n", receipt.Status)
-	fmt.Printf("Cumulative Gas Used: %s\n", receipt.CumulativeGasUsed.String())
-	fmt.Printf("Contract Address: %x\n", receipt.ContractAddress)
-	fmt.Printf("Bloom: %x\n", receipt.Bloom)
-	fmt.Printf("Logs:\n")
+// parseUnits parses a decimal amount such as "1.5" into an integer amount
+// of the smallest unit with the given decimals.
+func parseUnits(amount string, decimals uint8) (*big.Int, error) {
+	amount = strings.TrimSpace(amount)
+	whole, frac := amount, ""
 
-	for _, log := range receipt.Logs {
-		printLog(log)
+	if i := strings.Index(amount, "."); i >= 0 {
+		whole, frac = amount[:i], amount[i+1:]
 	}
-}
 
-func printLog(log *types.Log) {
-	fmt.Printf("\tAddress: %s\n", log.Address.Hex())
-	fmt.Printf("\tBlock Hash: %s\n", log.BlockHash.Hex())
-	fmt.Printf("\tBlock Number: %d\n", log.BlockNumber)
-	fmt.Printf("\tRemoved: %v\n", log.Removed)
-	fmt.Printf("\tData: %x\n", log.Data)
-	fmt.Printf("\tTopics:\n")
-	for _, topic := range log.Topics {
-		fmt.Printf("\t\t%x\n", topic)
+	if whole+frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return nil, fmt.Errorf("Invalid amount: %s", amount)
 	}
-	fmt.Println()
-}
 
-func printMethod(method *AbiMethod, data []byte) {
-	inputNames := getInputNamesString(method.Inputs)
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("Invalid amount: %s has more than %d decimals", amount, decimals)
+	}
 
-	fmt.Println("Method:", method.Name)
-	fmt.Println("Signature:", method.Signature)
-	fmt.Println("Inputs:", inputNames)
-	fmt.Printf("Values:")
+	value, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", int(decimals)-len(frac)), 10)
 
-	for i, input := range method.Inputs {
-		pos := i * 32
-		value := data[pos : pos+32]
+	return value, nil
+}
 
-		switch input.Type.String() {
-		case "int", "int8", "int16", "int32", "int64", "int256", "uint", "uint8", "uint16", "uint32", "uint64", "uint256":
-			valueInt, _ := parseBig256("0x" + hex.EncodeToString(value))
-			fmt.Printf("\t%v = %v\n", input.Name, valueInt)
-		case "address":
-			fmt.Printf("\t%v = 0x%x\n", input.Name, value[12:])
-		default:
-			fmt.Printf("\t%v = 0x%x\n", input.Name, value)
-		}
+func currentBlockNumber(client *wanclient.Client) (*big.Int, error) {
+	latestBlock, err := client.BlockByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println()
+	return latestBlock.Number(), nil
 }
 
-func printEvent(address string, method *AbiMethod, log *types.Log) {
-	inputNames := getInputNamesString(method.Inputs)
-
-	fmt.Println("Event:", method.Name)
-	fmt.Println("Address:", address)
-	fmt.Println("Signature:", method.Signature)
-	fmt.Println("Inputs:", inputNames)
-	fmt.Printf("Values:")
-
-	for i, input := range method.Inputs {
-
-		var value []byte
-
-		if i < len(log.Topics)-1 {
-			value = log.Topics[i+1].Bytes()
-		} else {
-			pos := (i - len(log.Topics) + 1) * 32
-			value = log.Data[pos : pos+32]
-		}
-
-		switch input.Type.String() {
-		case "int", "int8", "int16", "int32", "int64", "int256", "uint", "uint8", "uint16", "uint32", "uint64", "uint256":
-			valueInt, _ := parseBig256("0x" + hex.EncodeToString(value))
-			fmt.Printf("\t%v = %v\n", input.Name, valueInt)
-		case "address":
-			fmt.Printf("\t%v = 0x%x\n", input.Name, value[12:])
-		default:
-			fmt.Printf("\t%v = 0x%x\n", input.Name, value)
-		}
+func getArgumentTypes(inputs []AbiArgument) []*AbiType {
+	argTypes := make([]*AbiType, len(inputs))
+	for i, input := range inputs {
+		argTypes[i] = input.Type
 	}
+	return argTypes
+}
 
-	fmt.Println()
+func getInputNamesString(inputs []AbiArgument) string {
+	inputNames := make([]string, len(inputs))
+	for i, input := range inputs {
+		inputNames[i] = input.Name
+	}
+	return strings.Join(inputNames, ", ")
 }
 
 // from https://github.com/ethereum/go-ethereum/blob/master/common/math/big.go
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1000000000000000000", 18, "1"},
+		{"1500000000000000000", 18, "1.5"},
+		{"123456789012345678901", 18, "123.456789012345678901"},
+		{"-1500000000000000000", 18, "-1.5"},
+		{"-1", 6, "-0.000001"},
+		{"120", 0, "120"},
+		{"100", 2, "1"},
+	}
+
+	for _, test := range tests {
+		amount, _ := new(big.Int).SetString(test.amount, 10)
+
+		if got := formatUnits(amount, test.decimals); got != test.want {
+			t.Errorf("%s with %d decimals: got %s, want %s", test.amount, test.decimals, got, test.want)
+		}
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string // empty when parsing must fail
+	}{
+		{"1", 18, "1000000000000000000"},
+		{"1.5", 18, "1500000000000000000"},
+		{" 1.5 ", 18, "1500000000000000000"},
+		{".5", 18, "500000000000000000"},
+		{"5.", 18, "5000000000000000000"},
+		{"007", 2, "700"},
+		{"0.000000000000000001", 18, "1"},
+		{"1.23", 0, ""},
+		{"0.0000000000000000001", 18, ""},
+		{"1.234", 2, ""},
+		{"-1", 18, ""},
+		{"+1", 18, ""},
+		{"1e18", 18, ""},
+		{"1,5", 18, ""},
+		{"1.2.3", 18, ""},
+		{"", 18, ""},
+		{".", 18, ""},
+	}
+
+	for _, test := range tests {
+		got, err := parseUnits(test.amount, test.decimals)
+
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%q with %d decimals: expected an error, got %s", test.amount, test.decimals, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q with %d decimals: %s", test.amount, test.decimals, err)
+			continue
+		}
+
+		if got.String() != test.want {
+			t.Errorf("%q with %d decimals: got %s, want %s", test.amount, test.decimals, got, test.want)
+		}
+	}
+}
+
+func TestUnitsRoundTrip(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+	}{
+		{"0", 18},
+		{"1", 18},
+		{"1.5", 6},
+		{"1.5", 18},
+		{"0.000001", 6},
+		{"0.000000000000000001", 18},
+		{"123456.789", 18},
+		{"1000000", 0},
+	}
+
+	for _, test := range tests {
+		value, err := parseUnits(test.amount, test.decimals)
+		if err != nil {
+			t.Errorf("%s with %d decimals: %s", test.amount, test.decimals, err)
+			continue
+		}
+
+		if got := formatUnits(value, test.decimals); got != test.amount {
+			t.Errorf("%s with %d decimals: round trip gives %s", test.amount, test.decimals, got)
+		}
+	}
+}
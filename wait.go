@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+
+	wanchain "github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	wanclient "github.com/wanchain/go-wanchain/ethclient"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+const (
+	// droppedDelay is how long a known transaction may be missing from the
+	// node, with no other transaction of its nonce pending, before it is
+	// considered dropped.
+	droppedDelay = time.Minute
+
+	// replacementDepth is the number of blocks before the start of the wait
+	// searched for a replacement, which may be mined before the transaction
+	// disappears from the node.
+	replacementDepth = 100
+
+	// replacementBatchSize is the number of blocks requested per JSON-RPC
+	// batch while searching for a replacement.
+	replacementBatchSize = 50
+)
+
+// rpcMinedTx is a transaction as returned by eth_getTransactionByHash, with
+// the block it is mined in.
+type rpcMinedTx struct {
+	rpcPendingTx
+	BlockHash   *common.Hash `json:"blockHash"`
+	BlockNumber *hexutil.Big `json:"blockNumber"`
+}
+
+// waitResult is the result of tx wait: the mined transaction with the number
+// of confirmations it got.
+type waitResult struct {
+	*transactionDetails
+	BlockNumber   uint64 `json:"blockNumber"`
+	Confirmations uint64 `json:"confirmations"`
+}
+
+func (r *waitResult) printText() {
+	r.transactionDetails.printText()
+	fmt.Printf("Block: %d\n", r.BlockNumber)
+	fmt.Printf("Confirmations: %d\n", r.Confirmations)
+}
+
+func (r *waitResult) csvHeader() []string {
+	return append(r.transactionDetails.csvHeader(), "blockNumber", "confirmations")
+}
+
+func (r *waitResult) csvRecord() []string {
+	return append(r.transactionDetails.csvRecord(),
+		fmt.Sprint(r.BlockNumber),
+		fmt.Sprint(r.Confirmations),
+	)
+}
+
+// findReplacement looks for the mined transaction of the sender with the
+// nonce, from the newest block down to the start block. Blocks are requested
+// in JSON-RPC batches, and their transactions include the sender, so no
+// signature has to be recovered.
+func findReplacement(rpcClient *rpc.Client, from common.Address, nonce uint64, start, head uint64) (*common.Hash, error) {
+	for end := head + 1; end > start; {
+		first := start
+		if end-start > replacementBatchSize {
+			first = end - replacementBatchSize
+		}
+
+		blocks := make([]*struct{ Transactions []rpcPendingTx }, end-first)
+		elems := make([]rpc.BatchElem, len(blocks))
+
+		for i := range elems {
+			elems[i] = rpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeUint64(end - 1 - uint64(i)), true},
+				Result: &blocks[i],
+			}
+		}
+
+		if err := batchCallRPC(context.Background(), rpcClient, elems, replacementBatchSize); err != nil {
+			return nil, err
+		}
+
+		for _, block := range blocks {
+			if block == nil {
+				continue
+			}
+
+			for _, tx := range block.Transactions {
+				if tx.From == from && uint64(tx.Nonce) == nonce {
+					hash := tx.Hash
+					return &hash, nil
+				}
+			}
+		}
+
+		end = first
+	}
+
+	return nil, nil
+}
+
+// waitTransaction waits until a transaction is mined and has the requested
+// number of confirmations. New blocks are followed with a subscription when
+// the node supports it, and polled every --interval otherwise. A transaction
+// whose nonce is used by another one was replaced; one which disappears from
+// the node without that was dropped.
+func waitTransaction(c *cli.Context) error {
+	input := c.Args().First()
+	if input == "" {
+		input = c.String("hash")
+	}
+
+	if input == "" {
+		return cli.NewExitError("No tx hash provided", 1)
+	}
+
+	hash := common.HexToHash(input)
+
+	confirmations := uint64(1)
+	if n := c.Int64("confirmations"); n > 1 {
+		confirmations = uint64(n)
+	}
+
+	ctx := context.Background()
+
+	rpcClient := getRpcConnection()
+	defer rpcClient.Close()
+
+	client := wanclient.NewClient(rpcClient)
+
+	head, err := currentBlockNumber(client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	startBlock := head.Uint64()
+
+	heads := make(chan *types.Header, 16)
+	if sub, err := client.SubscribeNewHead(ctx, heads); err == nil {
+		defer sub.Unsubscribe()
+	}
+
+	ticker := time.NewTicker(c.Duration("interval"))
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time
+	if d := c.Duration("timeout"); d > 0 {
+		timeout = time.After(d)
+	}
+
+	var known *rpcMinedTx
+	var missingSince time.Time
+	status := ""
+
+	report := func(format string, args ...interface{}) {
+		if message := fmt.Sprintf(format, args...); message != status {
+			status = message
+			fmt.Fprintln(os.Stderr, message)
+		}
+	}
+
+	for {
+		var tx *rpcMinedTx
+		if err := rpcClient.CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		switch {
+		case tx == nil && known == nil:
+			report("Transaction %s not found, waiting for it", hash.Hex())
+
+		case tx == nil:
+			if missingSince.IsZero() {
+				missingSince = time.Now()
+			}
+
+			nonce, err := client.NonceAt(ctx, known.From, nil)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			if nonce > uint64(known.Nonce) {
+				current, err := currentBlockNumber(client)
+				if err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+
+				searchFrom := uint64(0)
+				if startBlock > replacementDepth {
+					searchFrom = startBlock - replacementDepth
+				}
+
+				replacement, err := findReplacement(rpcClient, known.From, uint64(known.Nonce), searchFrom, current.Uint64())
+				if err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+
+				if replacement != nil {
+					return cli.NewExitError(fmt.Sprintf("Transaction %s was replaced by %s", hash.Hex(), replacement.Hex()), 1)
+				}
+
+				return cli.NewExitError(fmt.Sprintf("Transaction %s was replaced, nonce %d of %s is used", hash.Hex(), known.Nonce, known.From.Hex()), 1)
+			}
+
+			// a replacement may still be pending, or the transaction may
+			// only be missing from the node for a moment
+			pendingNonce, err := client.PendingNonceAt(ctx, known.From)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			if pendingNonce > uint64(known.Nonce) {
+				report("Transaction %s is missing, another transaction with nonce %d is pending", hash.Hex(), known.Nonce)
+				break
+			}
+
+			if time.Since(missingSince) >= droppedDelay {
+				return cli.NewExitError(fmt.Sprintf("Transaction %s was dropped by the node", hash.Hex()), 1)
+			}
+
+			report("Transaction %s is missing from the node", hash.Hex())
+
+		case tx.BlockNumber == nil:
+			known, missingSince = tx, time.Time{}
+			report("Transaction %s is pending", hash.Hex())
+
+		default:
+			known, missingSince = tx, time.Time{}
+
+			current, err := currentBlockNumber(client)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			block := tx.BlockNumber.ToInt().Uint64()
+
+			confirmed := uint64(0)
+			if current.Uint64() >= block {
+				confirmed = current.Uint64() - block + 1
+			}
+
+			if confirmed < confirmations {
+				report("Transaction %s mined in block %d, %d of %d confirmations", hash.Hex(), block, confirmed, confirmations)
+				break
+			}
+
+			details, err := loadTransactionDetails(client, hash, c.String("abi"))
+
+			// removed by a reorganization meanwhile, the next pass tells
+			// whether it was replaced or dropped
+			if err == wanchain.NotFound {
+				break
+			}
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			// moved back to the pool by a reorganization meanwhile
+			if details.Receipt == nil {
+				break
+			}
+
+			result := &waitResult{
+				transactionDetails: details,
+				BlockNumber:        block,
+				Confirmations:      confirmed,
+			}
+
+			if err := printRecord(result); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			if details.Receipt.Status == types.ReceiptStatusFailed {
+				return cli.NewExitError(fmt.Sprintf("Transaction %s failed", hash.Hex()), 1)
+			}
+
+			return nil
+		}
+
+		select {
+		case <-heads:
+		case <-ticker.C:
+		case <-timeout:
+			return cli.NewExitError(fmt.Sprintf("Timed out waiting for transaction %s", hash.Hex()), 1)
+		}
+	}
+}